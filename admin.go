@@ -0,0 +1,105 @@
+package harhar
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminHandler returns an http.Handler for remotely inspecting the recorder:
+//
+//	GET  /har             the current archive (gzipped if the client accepts it)
+//	GET  /har?since=N     only entries added after entry number N, for incremental fetches
+//	GET  /har?since=TIME  only entries started after an RFC 3339 timestamp
+//	POST /clear           discard all recorded entries
+//	POST /pause           stop recording (see Recorder.Pause)
+//	POST /resume          resume recording
+//
+// Entries are numbered from 1 in the order they are added, and keep their
+// numbers when other entries are removed by Clear, Rotate or FlushAt, or
+// inserted before them by SortOutput. Responses to GET /har include an
+// X-Harhar-Entries header with the number of the last entry added, to use
+// as the next since value, and an X-Harhar-Enabled header reporting whether
+// recording is paused.
+//
+// POST requests from browsers are refused unless their Origin matches the
+// handler's host, so that other sites can't submit forms to it. Otherwise the
+// handler should be served on an internal address, it provides no
+// authentication.
+func (c *Recorder) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/har", c.serveAdminHAR)
+	mux.Handle("/clear", adminAction(c.Clear))
+	mux.Handle("/pause", adminAction(c.Pause))
+	mux.Handle("/resume", adminAction(c.Resume))
+	return mux
+}
+
+// adminAction returns a handler calling fn for POST requests.
+func adminAction(fn func()) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		fn()
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *Recorder) serveAdminHAR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h, last := c.snapshot()
+	if since := r.URL.Query().Get("since"); since != "" {
+		if n, err := strconv.ParseInt(since, 10, 64); err == nil {
+			if n < 0 {
+				http.Error(w, "since must not be negative", http.StatusBadRequest)
+				return
+			}
+			kept := h.Log.Entries[:0]
+			for _, ent := range h.Log.Entries {
+				if ent.seq > uint64(n) {
+					kept = append(kept, ent)
+				}
+			}
+			h.Log.Entries = kept
+		} else if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+			kept := h.Log.Entries[:0]
+			for _, ent := range h.Log.Entries {
+				if ent.StartTime().After(t) {
+					kept = append(kept, ent)
+				}
+			}
+			h.Log.Entries = kept
+		} else {
+			http.Error(w, "since must be an entry number or RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Harhar-Entries", strconv.FormatUint(last, 10))
+	w.Header().Set("X-Harhar-Enabled", strconv.FormatBool(c.Enabled()))
+	if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		json.NewEncoder(w).Encode(h)
+		return
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	json.NewEncoder(gz).Encode(h)
+	gz.Close()
+}
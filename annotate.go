@@ -0,0 +1,53 @@
+package harhar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Annotate attaches comment to the entry at index. Existing comments are
+// preserved, and the new comment is added on its own line.
+func (h *HAR) Annotate(index int, comment string) error {
+	if index < 0 || index >= len(h.Log.Entries) {
+		return fmt.Errorf("harhar: entry index %d out of range [0,%d)", index, len(h.Log.Entries))
+	}
+	addComment(&h.Log.Entries[index].Comment, comment)
+	return nil
+}
+
+// AnnotateMatching attaches comment to every entry for which match returns
+// true, and returns the number of entries annotated.
+func (h *HAR) AnnotateMatching(match func(*Entry) bool, comment string) int {
+	n := 0
+	for i := range h.Log.Entries {
+		if match(&h.Log.Entries[i]) {
+			addComment(&h.Log.Entries[i].Comment, comment)
+			n++
+		}
+	}
+	return n
+}
+
+// AnnotateURL attaches comment to every entry whose request URL contains
+// substr, and returns the number of entries annotated.
+func (h *HAR) AnnotateURL(substr, comment string) int {
+	return h.AnnotateMatching(func(e *Entry) bool {
+		return strings.Contains(e.Request.URL, substr)
+	}, comment)
+}
+
+// AnnotateStatus attaches comment to every entry whose response status is in
+// the range [min,max], and returns the number of entries annotated.
+func (h *HAR) AnnotateStatus(min, max int, comment string) int {
+	return h.AnnotateMatching(func(e *Entry) bool {
+		return e.Response.StatusCode >= min && e.Response.StatusCode <= max
+	}, comment)
+}
+
+func addComment(dst *string, comment string) {
+	if *dst == "" {
+		*dst = comment
+		return
+	}
+	*dst += "\n" + comment
+}
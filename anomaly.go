@@ -0,0 +1,129 @@
+package harhar
+
+import (
+	"math"
+	"sort"
+)
+
+// minimum number of entries for an endpoint before outliers are reported
+const minAnomalySamples = 5
+
+// Anomaly describes an entry whose response size or latency is a statistical
+// outlier compared to other entries for the same endpoint.
+type Anomaly struct {
+	// Entry is the index of the entry in the archive.
+	Entry int
+
+	// Endpoint the entry was grouped under (see Entry.Endpoint).
+	Endpoint string
+
+	// Metric is "time" (milliseconds) or "size" (response body bytes).
+	Metric string
+
+	// Value of the metric for this entry, and the median for the endpoint.
+	Value, Median float64
+
+	// Score is the robust z-score of Value (based on the median absolute
+	// deviation, or the mean absolute deviation if more than half the values
+	// are identical). Larger magnitudes are more unusual.
+	Score float64
+}
+
+// FindAnomalies returns the entries whose total time or response size is an
+// outlier for their endpoint, with a robust z-score magnitude of at least
+// threshold (3.5 is a common choice). Endpoints with fewer than 5 entries
+// are skipped. Results are ordered by decreasing score magnitude.
+func FindAnomalies(h *HAR, threshold float64) []Anomaly {
+	groups := make(map[string][]int)
+	for i := range h.Log.Entries {
+		ep := h.Log.Entries[i].Endpoint()
+		groups[ep] = append(groups[ep], i)
+	}
+
+	var res []Anomaly
+	for ep, idxs := range groups {
+		if len(idxs) < minAnomalySamples {
+			continue
+		}
+		metrics := map[string]func(*Entry) float64{
+			"time": func(e *Entry) float64 { return float64(e.Time) },
+			"size": func(e *Entry) float64 { return float64(e.Response.BodySize) },
+		}
+		for name, get := range metrics {
+			vals := make([]float64, len(idxs))
+			for j, i := range idxs {
+				vals[j] = get(&h.Log.Entries[i])
+			}
+			med, mad := medianMAD(vals)
+			meanAD := meanAbsDev(vals, med)
+			for j, i := range idxs {
+				var score float64
+				switch {
+				case mad != 0:
+					// 0.6745 scales the MAD to be comparable to a standard deviation
+					score = 0.6745 * (vals[j] - med) / mad
+				case meanAD != 0:
+					// more than half the values are identical, fall back
+					// to the mean absolute deviation, scaled likewise
+					score = (vals[j] - med) / (1.253314 * meanAD)
+				case vals[j] > med:
+					score = math.Inf(1)
+				case vals[j] < med:
+					score = math.Inf(-1)
+				}
+				if math.Abs(score) >= threshold {
+					res = append(res, Anomaly{
+						Entry: i, Endpoint: ep, Metric: name,
+						Value: vals[j], Median: med, Score: score,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		si, sj := math.Abs(res[i].Score), math.Abs(res[j].Score)
+		if si != sj {
+			return si > sj
+		}
+		return res[i].Entry < res[j].Entry
+	})
+	return res
+}
+
+// medianMAD returns the median and median absolute deviation of vals.
+func medianMAD(vals []float64) (float64, float64) {
+	sorted := append([]float64(nil), vals...)
+	sort.Float64s(sorted)
+	med := median(sorted)
+	devs := make([]float64, len(sorted))
+	for i, v := range sorted {
+		devs[i] = math.Abs(v - med)
+	}
+	sort.Float64s(devs)
+	return med, median(devs)
+}
+
+// meanAbsDev returns the mean absolute deviation of vals from med.
+func meanAbsDev(vals []float64, med float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += math.Abs(v - med)
+	}
+	return sum / float64(len(vals))
+}
+
+// median of sorted values
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,213 @@
+package harhar
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AssertOptions controls how CompareResponses checks a replayed response
+// against a recorded one.
+type AssertOptions struct {
+	// Headers lists response headers whose values must match. Other headers
+	// are not compared.
+	Headers []string
+
+	// IgnorePaths lists JSON body fields which are not compared, as
+	// dot-separated keys or array indexes. "*" matches any single key or
+	// index, e.g. "data.*.updatedAt".
+	IgnorePaths []string
+
+	// IgnoreBody disables body comparison.
+	IgnoreBody bool
+}
+
+// Mismatch describes one difference between a recorded and a replayed
+// response.
+type Mismatch struct {
+	// Field which differs: "status", "header <Name>", "body", or
+	// "body.<path>" for JSON bodies.
+	Field    string `json:"field"`
+	Recorded string `json:"recorded"`
+	Replayed string `json:"replayed"`
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: recorded %s, replayed %s", m.Field, m.Recorded, m.Replayed)
+}
+
+// CompareResponses returns the differences between the responses of a
+// recorded and a replayed entry. JSON bodies are compared structurally, so
+// formatting and key order don't matter.
+func CompareResponses(recorded, replayed *Entry, opts AssertOptions) []Mismatch {
+	var res []Mismatch
+	if a, b := recorded.Response.StatusCode, replayed.Response.StatusCode; a != b {
+		res = append(res, Mismatch{Field: "status", Recorded: strconv.Itoa(a), Replayed: strconv.Itoa(b)})
+	}
+
+	if len(opts.Headers) > 0 {
+		selected := func(pairs []NameValuePair) []NameValuePair {
+			var kept []NameValuePair
+			for _, p := range pairs {
+				if containsFold(opts.Headers, p.Name) {
+					kept = append(kept, p)
+				}
+			}
+			return kept
+		}
+		for _, d := range diffHeaders(selected(recorded.Response.Headers), selected(replayed.Response.Headers), nil) {
+			res = append(res, Mismatch{Field: "header " + http.CanonicalHeaderKey(d.Name), Recorded: d.Old, Replayed: d.New})
+		}
+	}
+
+	if !opts.IgnoreBody {
+		res = append(res, compareBodies(&recorded.Response.Body, &replayed.Response.Body, opts.IgnorePaths)...)
+	}
+	return res
+}
+
+func compareBodies(a, b *BodyResponseType, ignorePaths []string) []Mismatch {
+	at, bt := a.Text(), b.Text()
+	av, aerr := decodeJSON(at)
+	bv, berr := decodeJSON(bt)
+	if aerr != nil || berr != nil {
+		if at != bt || a.Encoding != b.Encoding {
+			return []Mismatch{{Field: "body", Recorded: abbreviate(at), Replayed: abbreviate(bt)}}
+		}
+		return nil
+	}
+
+	var ignore [][]string
+	for _, p := range ignorePaths {
+		ignore = append(ignore, strings.Split(p, "."))
+	}
+	var res []Mismatch
+	compareJSON(&res, "body", nil, av, bv, ignore)
+	return res
+}
+
+// decodeJSON parses s, keeping numbers exact.
+func decodeJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// compareJSON appends a mismatch for each differing value in a and b.
+func compareJSON(res *[]Mismatch, field string, path []string, a, b interface{}, ignore [][]string) {
+	if ignoredPath(path, ignore) {
+		return
+	}
+	child := func(key string) (string, []string) {
+		return field + "." + key, append(path[:len(path):len(path)], key)
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ak, ok := av[k]
+			if !ok {
+				ak = jsonMissing{}
+			}
+			bk, ok := bv[k]
+			if !ok {
+				bk = jsonMissing{}
+			}
+			f, p := child(k)
+			compareJSON(res, f, p, ak, bk, ignore)
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		n := len(av)
+		if len(bv) > n {
+			n = len(bv)
+		}
+		for i := 0; i < n; i++ {
+			var ai, bi interface{} = jsonMissing{}, jsonMissing{}
+			if i < len(av) {
+				ai = av[i]
+			}
+			if i < len(bv) {
+				bi = bv[i]
+			}
+			f, p := child(strconv.Itoa(i))
+			compareJSON(res, f, p, ai, bi, ignore)
+		}
+		return
+	}
+
+	as, bs := jsonString(a), jsonString(b)
+	if as != bs {
+		*res = append(*res, Mismatch{Field: field, Recorded: abbreviate(as), Replayed: abbreviate(bs)})
+	}
+}
+
+// jsonMissing stands in for object keys and array elements present in only
+// one of the compared values.
+type jsonMissing struct{}
+
+func jsonString(v interface{}) string {
+	if _, ok := v.(jsonMissing); ok {
+		return "(missing)"
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// ignoredPath returns true if path matches any of the ignore patterns.
+func ignoredPath(path []string, ignore [][]string) bool {
+	for _, pattern := range ignore {
+		if len(pattern) != len(path) {
+			continue
+		}
+		match := true
+		for i := range pattern {
+			if pattern[i] != "*" && pattern[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// abbreviate shortens long values and collapses whitespace for display.
+func abbreviate(s string) string {
+	const max = 80
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
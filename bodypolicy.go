@@ -0,0 +1,44 @@
+package harhar
+
+import (
+	"mime"
+	"strings"
+)
+
+// DefaultSkipBodyTypes lists MIME types of static assets whose contents are
+// rarely useful in a HAR, for use with Recorder.SkipBodyTypes.
+var DefaultSkipBodyTypes = []string{
+	"image/*",
+	"video/*",
+	"audio/*",
+	"font/*",
+	"application/font-*",
+	"application/x-font-*",
+	"application/octet-stream",
+}
+
+// skipBodies drops body contents with a MIME type listed in SkipBodyTypes.
+func (c *Recorder) skipBodies(ent *Entry) {
+	if len(c.SkipBodyTypes) == 0 {
+		return
+	}
+	if c.skipMIMEType(ent.Request.Body.MIMEType) {
+		ent.Request.Body.Content = ""
+		ent.Request.Body.Params = nil
+		ent.Request.Body.Encoding = ""
+	}
+	if c.skipMIMEType(ent.Response.Body.MIMEType) {
+		ent.Response.Body.Content = ""
+		ent.Response.Body.Encoding = ""
+	}
+}
+
+func (c *Recorder) skipMIMEType(mimeType string) bool {
+	if mimeType == "" {
+		return false
+	}
+	if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mt
+	}
+	return matchAnyGlob(c.SkipBodyTypes, strings.ToLower(mimeType))
+}
@@ -0,0 +1,91 @@
+package harhar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// storeBody writes content into dir named by its SHA-256 hash, and returns
+// the file name. Identical bodies are only stored once.
+func storeBody(dir, content string) (string, error) {
+	sum := sha256.Sum256([]byte(content))
+	name := hex.EncodeToString(sum[:])
+	fn := filepath.Join(dir, name)
+	if _, err := os.Stat(fn); err == nil {
+		return name, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// write then rename, so concurrent recorders never see partial files
+	tmp, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.WriteString(content)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fn)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return name, nil
+}
+
+// externalizeBodies moves the entry's body contents into c.BodyDir. Bodies
+// are left inline if they cannot be written.
+func (c *Recorder) externalizeBodies(ent *Entry) {
+	if c.BodyDir == "" {
+		return
+	}
+	if ent.Request.Body.Content != "" {
+		name, err := storeBody(c.BodyDir, ent.Request.Body.Content)
+		if err != nil {
+			log.Println("unable to store request body ", err)
+		} else {
+			ent.Request.Body.FileRef = name
+			ent.Request.Body.Content = ""
+		}
+	}
+	if ent.Response.Body.Content != "" {
+		name, err := storeBody(c.BodyDir, ent.Response.Body.Content)
+		if err != nil {
+			log.Println("unable to store response body ", err)
+		} else {
+			ent.Response.Body.FileRef = name
+			ent.Response.Body.Content = ""
+		}
+	}
+}
+
+// LoadBodies restores body contents which were stored externally (see
+// Recorder.BodyDir) from dir back into the archive. Files which cannot be
+// read are skipped and reported in the returned error.
+func (h *HAR) LoadBodies(dir string) error {
+	var errs []error
+	load := func(ref string, dst *string) {
+		if ref == "" || *dst != "" {
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.Base(ref)))
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		*dst = string(data)
+	}
+	for i := range h.Log.Entries {
+		ent := &h.Log.Entries[i]
+		load(ent.Request.Body.FileRef, &ent.Request.Body.Content)
+		load(ent.Response.Body.FileRef, &ent.Response.Body.Content)
+	}
+	return errors.Join(errs...)
+}
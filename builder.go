@@ -0,0 +1,158 @@
+package harhar
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// EntryBuilder constructs synthetic entries without real HTTP traffic, e.g.
+// to hand-author fixture archives for a Replayer:
+//
+//	ent, err := harhar.NewEntryBuilder().
+//		Method("GET").URL("https://example.com/api/items").
+//		Status(200).JSONBody(items).Build()
+//
+// Errors are deferred until Build.
+type EntryBuilder struct {
+	ent   Entry
+	start time.Time
+	err   error
+}
+
+// NewEntryBuilder returns a builder for a GET request with an empty 200 OK
+// response, started at the current time.
+func NewEntryBuilder() *EntryBuilder {
+	return &EntryBuilder{
+		start: time.Now(),
+		ent: Entry{
+			Request: Request{
+				Method:      http.MethodGet,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []Cookie{},
+				Headers:     []NameValuePair{},
+				QueryParams: []NameValuePair{},
+				HeadersSize: -1,
+			},
+			Response: Response{
+				StatusCode:  http.StatusOK,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []Cookie{},
+				Headers:     []NameValuePair{},
+				HeadersSize: -1,
+			},
+		},
+	}
+}
+
+// Method sets the request method.
+func (b *EntryBuilder) Method(method string) *EntryBuilder {
+	b.ent.Request.Method = method
+	return b
+}
+
+// URL sets the request URL, and the query parameters parsed from it.
+func (b *EntryBuilder) URL(u string) *EntryBuilder {
+	pu, err := url.Parse(u)
+	if err != nil {
+		b.setErr(err)
+		return b
+	}
+	b.ent.Request.URL = pu.String()
+	b.ent.Request.QueryParams = parseQuery(pu.RawQuery)
+	return b
+}
+
+// Header adds a request header.
+func (b *EntryBuilder) Header(name, value string) *EntryBuilder {
+	b.ent.Request.Headers = append(b.ent.Request.Headers, NameValuePair{Name: name, Value: value})
+	return b
+}
+
+// RequestBody sets the request body and its MIME type.
+func (b *EntryBuilder) RequestBody(mimeType, content string) *EntryBuilder {
+	b.ent.Request.Body = BodyType{MIMEType: mimeType, Content: content}
+	return b
+}
+
+// JSONRequest sets the request body to v encoded as JSON.
+func (b *EntryBuilder) JSONRequest(v interface{}) *EntryBuilder {
+	data, err := json.Marshal(v)
+	b.setErr(err)
+	return b.RequestBody("application/json", string(data))
+}
+
+// Status sets the response status code.
+func (b *EntryBuilder) Status(code int) *EntryBuilder {
+	b.ent.Response.StatusCode = code
+	return b
+}
+
+// ResponseHeader adds a response header.
+func (b *EntryBuilder) ResponseHeader(name, value string) *EntryBuilder {
+	b.ent.Response.Headers = append(b.ent.Response.Headers, NameValuePair{Name: name, Value: value})
+	return b
+}
+
+// Body sets the response body and its MIME type.
+func (b *EntryBuilder) Body(mimeType, content string) *EntryBuilder {
+	b.ent.Response.Body = BodyResponseType{MIMEType: mimeType, Content: content}
+	return b
+}
+
+// JSONBody sets the response body to v encoded as JSON.
+func (b *EntryBuilder) JSONBody(v interface{}) *EntryBuilder {
+	data, err := json.Marshal(v)
+	b.setErr(err)
+	return b.Body("application/json", string(data))
+}
+
+// Timing sets the start time and total duration of the entry. The duration
+// is attributed to waiting for the response.
+func (b *EntryBuilder) Timing(start time.Time, d time.Duration) *EntryBuilder {
+	b.start = start
+	b.ent.Time = int(d.Milliseconds())
+	return b
+}
+
+// PageRef sets the ID of the page the entry belongs to.
+func (b *EntryBuilder) PageRef(id string) *EntryBuilder {
+	b.ent.PageRef = id
+	return b
+}
+
+// Comment sets the entry comment.
+func (b *EntryBuilder) Comment(comment string) *EntryBuilder {
+	b.ent.Comment = comment
+	return b
+}
+
+// Build returns the constructed entry, or the first error encountered while
+// building it.
+func (b *EntryBuilder) Build() (Entry, error) {
+	if b.err != nil {
+		return Entry{}, b.err
+	}
+	ent := b.ent
+	ent.Request.Headers = append([]NameValuePair{}, ent.Request.Headers...)
+	ent.Request.QueryParams = append([]NameValuePair{}, ent.Request.QueryParams...)
+	ent.Response.Headers = append([]NameValuePair{}, ent.Response.Headers...)
+
+	ent.Start = b.start.Format(time.RFC3339Nano)
+	ent.Timings.Wait = ent.Time
+	ent.Request.BodySize = len(ent.Request.Body.Content)
+	ent.Response.StatusText = http.StatusText(ent.Response.StatusCode)
+	ent.Response.Body.Size = len(ent.Response.Body.Content)
+	ent.Response.BodySize = ent.Response.Body.Size
+	if ent.Response.Body.MIMEType == "" {
+		ent.Response.Body.MIMEType = "application/octet-stream"
+	}
+	return ent, nil
+}
+
+func (b *EntryBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,98 @@
+package harhar
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// makeCache builds the Entry.Cache section from the caching headers of a
+// request/response pair. After describes how the response may be cached (nil
+// if it is not cacheable), and Before is only populated for conditional
+// requests that were answered with 304 Not Modified.
+func makeCache(req *http.Request, resp *http.Response, now time.Time) CacheState {
+	cs := CacheState{}
+	if req == nil || resp == nil {
+		return cs
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		etag := req.Header.Get("If-None-Match")
+		since := req.Header.Get("If-Modified-Since")
+		if etag != "" || since != "" {
+			cs.Before = &CacheInfo{
+				ETag:       etag,
+				LastAccess: formatHTTPDate(since),
+			}
+		}
+	}
+
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return cs
+	}
+
+	after := &CacheInfo{
+		ETag:       resp.Header.Get("ETag"),
+		LastAccess: now.Format(time.RFC3339Nano),
+	}
+	if cs.Before != nil && after.ETag == "" {
+		// 304s are not required to repeat the validator
+		after.ETag = cs.Before.ETag
+	}
+
+	// max-age takes precedence over Expires per RFC 7234
+	date := now
+	if d, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		date = d
+	}
+	if v, ok := directives["s-maxage"]; ok && v != "" {
+		directives["max-age"] = v
+	}
+	if v, ok := directives["max-age"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			after.Expires = date.Add(time.Duration(secs) * time.Second).Format(time.RFC3339Nano)
+		}
+	} else if exp := resp.Header.Get("Expires"); exp != "" {
+		after.Expires = formatHTTPDate(exp)
+		if after.Expires == "" {
+			// invalid Expires values (e.g. "0") mean already expired
+			after.Expires = date.Format(time.RFC3339Nano)
+		}
+	}
+
+	after.LastModified = formatHTTPDate(resp.Header.Get("Last-Modified"))
+
+	if len(directives) == 0 && after.ETag == "" && after.Expires == "" && after.LastModified == "" {
+		// no caching information to describe
+		return cs
+	}
+	cs.After = after
+	return cs
+}
+
+// parseCacheControl splits a Cache-Control header into lowercase directive
+// names and their (unquoted) values.
+func parseCacheControl(v string) map[string]string {
+	res := make(map[string]string)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, _ := strings.Cut(part, "=")
+		res[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(val), `"`)
+	}
+	return res
+}
+
+// formatHTTPDate converts an HTTP-date header value into ISO 8601, or returns
+// an empty string if it cannot be parsed.
+func formatHTTPDate(v string) string {
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,79 @@
+package harhar
+
+import (
+	"io"
+	"time"
+)
+
+// chunkRecorder wraps a response body and records the size and arrival time
+// of each chunk read from it.
+type chunkRecorder struct {
+	io.ReadCloser
+	start  time.Time
+	now    func() time.Time
+	chunks []Chunk
+
+	// a read which fills the caller's buffer is probably not the end of
+	// the chunk, so the next read continues it
+	continued bool
+}
+
+func (cr *chunkRecorder) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	if n > 0 {
+		if cr.continued && len(cr.chunks) > 0 {
+			cr.chunks[len(cr.chunks)-1].Size += n
+		} else {
+			cr.chunks = append(cr.chunks, Chunk{
+				Size: n,
+				Time: int(cr.now().Sub(cr.start).Milliseconds()),
+			})
+		}
+		cr.continued = n == len(p)
+	}
+	return n, err
+}
+
+// chunkReplayer returns data in the recorded chunks, each no earlier than its
+// recorded arrival time relative to the first Read.
+type chunkReplayer struct {
+	data   []byte
+	chunks []Chunk
+	start  time.Time
+
+	chunk int // current chunk
+	left  int // bytes left in the current chunk
+}
+
+func (cr *chunkReplayer) Read(p []byte) (int, error) {
+	if len(cr.data) == 0 {
+		return 0, io.EOF
+	}
+	if cr.start.IsZero() {
+		cr.start = time.Now()
+	}
+	if cr.left == 0 {
+		if cr.chunk >= len(cr.chunks) {
+			// the recording didn't cover the whole body
+			n := copy(p, cr.data)
+			cr.data = cr.data[n:]
+			return n, nil
+		}
+		next := cr.chunks[cr.chunk]
+		cr.chunk++
+		cr.left = next.Size
+		time.Sleep(time.Until(cr.start.Add(time.Duration(next.Time) * time.Millisecond)))
+	}
+
+	if len(p) > cr.left {
+		p = p[:cr.left]
+	}
+	n := copy(p, cr.data)
+	cr.data = cr.data[n:]
+	cr.left -= n
+	return n, nil
+}
+
+func (cr *chunkReplayer) Close() error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/pbnjay/harhar"
+)
+
+func anomaliesCommand(args []string) error {
+	fs := flag.NewFlagSet("anomalies", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 3.5, "minimum robust z-score to report")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar anomalies <results.har> [--threshold 3.5]")
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	found := harhar.FindAnomalies(h, *threshold)
+	for _, a := range found {
+		ent := h.Log.Entries[a.Entry]
+		unit := "ms"
+		if a.Metric == "size" {
+			unit = "b"
+		}
+		fmt.Printf("#%-5d %-5s %8.0f%s (median %.0f%s, score %+.1f)  %s %s\n",
+			a.Entry, a.Metric, a.Value, unit, a.Median, unit, a.Score, ent.Request.Method, ent.Request.URL)
+	}
+	fmt.Printf("%d suspects in %d entries\n", len(found), len(h.Log.Entries))
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/pbnjay/harhar"
+)
+
+func compactCommand(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	output := fs.String("o", "", "output `filename` (required)")
+	strip := fs.Bool("strip-bodies", false, "remove request and response body content")
+	dedupe := fs.Bool("dedupe", false, "remove duplicate entries")
+	gz := fs.Bool("gzip", false, "gzip-compress the output")
+	sortFields := fs.Bool("sort-fields", false, "sort headers, query parameters and cookies by name")
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 || *output == "" {
+		return errors.New("usage: harhar compact <input.har> -o <output.har> [--strip-bodies] [--dedupe] [--sort-fields] [--gzip] [--tag t]")
+	}
+
+	h, err := readSelected(files[0], opts)
+	if err != nil {
+		return err
+	}
+	before := len(h.Log.Entries)
+	if *dedupe {
+		log.Printf("removed %d duplicate entries\n", h.Dedupe())
+	}
+	if *strip {
+		h.StripBodies()
+	}
+	if *sortFields {
+		h.SortFields()
+	}
+	h.SortEntries()
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if *gz {
+		zw := gzip.NewWriter(f)
+		if _, err = h.WriteTo(zw); err != nil {
+			return err
+		}
+		err = zw.Close()
+	} else {
+		_, err = h.WriteTo(f)
+	}
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	log.Printf("wrote %s (%d of %d entries, %.1fkb)\n", *output, len(h.Log.Entries), before, float64(st.Size())/1024.0)
+	return f.Close()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileJar is a cookie jar which can be saved to and loaded from a JSON file,
+// so cookies persist across runs.
+type fileJar struct {
+	*cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[jarKey]jarCookie
+}
+
+type jarKey struct{ domain, path, name string }
+
+// jarCookie is a cookie as stored in the file, with the URL which set it.
+type jarCookie struct {
+	URL      string     `json:"url"`
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+}
+
+// loadJar returns a jar holding the cookies saved in filename, or an empty
+// jar if it doesn't exist yet.
+func loadJar(filename string) (*fileJar, error) {
+	cj, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	j := &fileJar{Jar: cj, cookies: make(map[jarKey]jarCookie)}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var saved []jarCookie
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	for _, c := range saved {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, err
+		}
+		hc := &http.Cookie{
+			Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path,
+			Secure: c.Secure, HttpOnly: c.HTTPOnly,
+		}
+		if c.Expires != nil {
+			hc.Expires = *c.Expires
+		}
+		j.SetCookies(u, []*http.Cookie{hc})
+	}
+	return j, nil
+}
+
+// SetCookies implements http.CookieJar, remembering the cookies for Save.
+func (j *fileJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		k := jarKey{c.Domain, c.Path, c.Name}
+		if k.domain == "" {
+			k.domain = u.Hostname()
+		}
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if c.MaxAge < 0 || (!expires.IsZero() && expires.Before(now)) {
+			delete(j.cookies, k)
+			continue
+		}
+		saved := jarCookie{
+			URL: (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(), Name: c.Name, Value: c.Value,
+			Domain: c.Domain, Path: c.Path, Secure: c.Secure, HTTPOnly: c.HttpOnly,
+		}
+		if !expires.IsZero() {
+			saved.Expires = &expires
+		}
+		j.cookies[k] = saved
+	}
+}
+
+// Save writes the unexpired cookies to filename.
+func (j *fileJar) Save(filename string) error {
+	j.mu.Lock()
+	saved := make([]jarCookie, 0, len(j.cookies))
+	now := time.Now()
+	for _, c := range j.cookies {
+		if c.Expires == nil || c.Expires.After(now) {
+			saved = append(saved, c)
+		}
+	}
+	j.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0600)
+}
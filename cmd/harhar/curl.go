@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/pbnjay/harhar"
+)
+
+func toCurlCommand(args []string) error {
+	fs := flag.NewFlagSet("to-curl", flag.ExitOnError)
+	var indexes stringsFlag
+	fs.Var(&indexes, "i", "only print the entry at `index` (repeatable)")
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar to-curl <results.har> [-i index] [--tag t]")
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	selected := make([]int, 0, len(h.Log.Entries))
+	for _, s := range indexes {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 || i >= len(h.Log.Entries) {
+			return fmt.Errorf("invalid entry index %q", s)
+		}
+		selected = append(selected, i)
+	}
+	if len(indexes) == 0 {
+		for i := range h.Log.Entries {
+			selected = append(selected, i)
+		}
+	}
+
+	for _, i := range selected {
+		if !opts.Match(&h.Log.Entries[i]) {
+			continue
+		}
+		cmd, err := h.Log.Entries[i].ToCurl()
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		fmt.Println(cmd)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pbnjay/harhar"
+)
+
+func diffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "output `format`, text or json")
+	latency := fs.Int("latency", 0, "also report unchanged entries whose time changed by at least `ms`")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return errors.New("usage: harhar diff <old.har> <new.har> [--format text|json] [--latency ms]")
+	}
+
+	before, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	after, err := harhar.ReadFile(files[1])
+	if err != nil {
+		return err
+	}
+
+	var diffs []harhar.EntryDiff
+	for _, d := range harhar.Diff(before, after, harhar.DefaultDiffIgnoreHeaders...) {
+		if d.Kind != harhar.DiffUnchanged || (*latency > 0 && abs(d.TimeDelta) >= *latency) {
+			diffs = append(diffs, d)
+		}
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if diffs == nil {
+			diffs = []harhar.EntryDiff{}
+		}
+		return enc.Encode(diffs)
+	case "text":
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	for _, d := range diffs {
+		switch d.Kind {
+		case harhar.DiffAdded:
+			fmt.Printf("+ %s %s (%d)\n", d.Method, d.URL, d.NewStatus)
+			continue
+		case harhar.DiffRemoved:
+			fmt.Printf("- %s %s (%d)\n", d.Method, d.URL, d.OldStatus)
+			continue
+		}
+		fmt.Printf("~ %s %s (%+dms)\n", d.Method, d.URL, d.TimeDelta)
+		if d.OldStatus != d.NewStatus {
+			fmt.Printf("    status: %d -> %d\n", d.OldStatus, d.NewStatus)
+		}
+		for _, h := range d.Headers {
+			fmt.Printf("    %s: %q -> %q\n", h.Name, h.Old, h.New)
+		}
+		if d.BodyChanged {
+			fmt.Println("    body changed")
+		}
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pbnjay/harhar"
+)
+
+// exporters write an archive in another format. name is a title for formats
+// which have one.
+var exporters = map[string]func(h *harhar.HAR, w io.Writer, name string) error{
+	"postman": func(h *harhar.HAR, w io.Writer, name string) error {
+		return h.WritePostman(w, name)
+	},
+	"openapi": func(h *harhar.HAR, w io.Writer, name string) error {
+		return h.WriteOpenAPI(w, name)
+	},
+	"ndjson": func(h *harhar.HAR, w io.Writer, name string) error {
+		return h.WriteNDJSON(w)
+	},
+	"csv": func(h *harhar.HAR, w io.Writer, name string) error {
+		return h.WriteCSV(w)
+	},
+	"k6": func(h *harhar.HAR, w io.Writer, name string) error {
+		return h.WriteK6(w)
+	},
+	"vegeta": func(h *harhar.HAR, w io.Writer, name string) error {
+		return h.WriteVegeta(w)
+	},
+}
+
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "", "output `format`: "+exportFormats())
+	output := fs.String("o", "", "output `filename` (default stdout)")
+	name := fs.String("name", "", "collection or document `title` (default input name)")
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 || *format == "" {
+		return errors.New("usage: harhar export <results.har> --format " + exportFormats() + " [-o output] [--name title] [--tag t]")
+	}
+	export, ok := exporters[*format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+
+	var h *harhar.HAR
+	if *format != "ndjson" {
+		// ndjson is streamed instead, for archives too large to load
+		if h, err = readSelected(files[0], opts); err != nil {
+			return err
+		}
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(files[0]), filepath.Ext(files[0]))
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	if h == nil {
+		err = exportNDJSON(out, files[0], opts)
+	} else {
+		err = export(h, out, *name)
+	}
+	if err != nil || out == os.Stdout {
+		return err
+	}
+	return out.Close()
+}
+
+// exportNDJSON writes the entries of a HAR file matched by opts to w one at a
+// time, as HAR.WriteNDJSON does.
+func exportNDJSON(w io.Writer, filename string, opts harhar.FilterOptions) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err = harhar.Stream(f, func(ent harhar.Entry) error {
+		if !opts.Match(&ent) {
+			return nil
+		}
+		return enc.Encode(&ent)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func exportFormats() string {
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbnjay/harhar"
+)
+
+// requestFlags describe the requests to make, as curl-like flags.
+type requestFlags struct {
+	method   string
+	data     stringsFlag
+	dataFile string
+	json     string
+	headers  stringsFlag
+	form     stringsFlag
+	cookies  stringsFlag
+
+	basic  string
+	bearer string
+	netrc  map[string]netrcLogin
+}
+
+func (rf *requestFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&rf.method, "X", "", "request `method` (default GET, or POST with a body)")
+	fs.Var(&rf.data, "d", "send `data` as a urlencoded body, or @file to read it (repeatable, joined with &)")
+	fs.Var(&rf.data, "data", "same as -d")
+	fs.StringVar(&rf.dataFile, "data-file", "", "send the contents of `file` as the body, unmodified")
+	fs.StringVar(&rf.json, "json", "", "send `json` (or @file) as the body, and accept JSON responses")
+	fs.Var(&rf.headers, "H", "add the request header `\"Name: value\"` (repeatable)")
+	fs.Var(&rf.cookies, "cookie", "send the cookie `\"name=value\"` with every request (repeatable)")
+	fs.StringVar(&rf.basic, "basic", "", "authenticate with HTTP basic auth as `user:password`")
+	fs.StringVar(&rf.bearer, "bearer", "", "authenticate with the bearer `token`")
+	fs.Var(&rf.form, "form", "add the multipart form field `name=value`, or name=@file to upload a file (repeatable)")
+}
+
+// body returns the request body and its content type, if any.
+func (rf *requestFlags) body() ([]byte, string, error) {
+	n := 0
+	for _, set := range []bool{len(rf.data) > 0, rf.dataFile != "", rf.json != "", len(rf.form) > 0} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return nil, "", errors.New("only one of -d, --data-file, --json and --form may be used")
+	}
+
+	switch {
+	case len(rf.data) > 0:
+		parts := make([]string, len(rf.data))
+		for i, d := range rf.data {
+			b, err := readArg(d)
+			if err != nil {
+				return nil, "", err
+			}
+			parts[i] = string(b)
+		}
+		return []byte(strings.Join(parts, "&")), "application/x-www-form-urlencoded", nil
+	case rf.dataFile != "":
+		b, err := os.ReadFile(rf.dataFile)
+		return b, "", err
+	case rf.json != "":
+		b, err := readArg(rf.json)
+		return b, "application/json", err
+	case len(rf.form) > 0:
+		return multipartBody(rf.form)
+	}
+	return nil, "", nil
+}
+
+// fetchJob is a single request to make.
+type fetchJob struct {
+	method, url string
+	headers     []string // "Name: value"
+	body        []byte
+	contentType string
+}
+
+// defaultJob returns the request described by the flags, without a URL.
+func (rf *requestFlags) defaultJob() (fetchJob, error) {
+	body, contentType, err := rf.body()
+	if err != nil {
+		return fetchJob{}, err
+	}
+	return fetchJob{method: rf.method, headers: rf.headers, body: body, contentType: contentType}, nil
+}
+
+// readJobs reads requests from r, one per line, as either a URL or
+// "METHOD URL [headerfile] [bodyfile]". The header file holds "Name: value"
+// lines. Blank lines and lines starting with # are skipped. URLs on their own
+// are requested like defaults, which holds the method, headers and body given
+// by the flags.
+func readJobs(r io.Reader, defaults fetchJob) ([]fetchJob, error) {
+	var jobs []fetchJob
+	sc := bufio.NewScanner(r)
+	for lineno := 1; sc.Scan(); lineno++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) == 1 {
+			job := defaults
+			job.url = fields[0]
+			jobs = append(jobs, job)
+			continue
+		}
+		if len(fields) > 4 {
+			return nil, fmt.Errorf("line %d: expected \"METHOD URL [headerfile] [bodyfile]\"", lineno)
+		}
+
+		job := fetchJob{method: strings.ToUpper(fields[0]), url: fields[1], headers: defaults.headers}
+		if len(fields) > 2 && fields[2] != "-" {
+			data, err := os.ReadFile(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineno, err)
+			}
+			for _, h := range strings.Split(string(data), "\n") {
+				if h = strings.TrimSpace(h); h != "" {
+					job.headers = append(job.headers[:len(job.headers):len(job.headers)], h)
+				}
+			}
+		}
+		if len(fields) > 3 {
+			var err error
+			if job.body, err = os.ReadFile(fields[3]); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineno, err)
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, sc.Err()
+}
+
+// newRequest builds the request for a job.
+func (rf *requestFlags) newRequest(job fetchJob) (*http.Request, error) {
+	method := job.method
+	if method == "" {
+		method = http.MethodGet
+		if job.body != nil {
+			method = http.MethodPost
+		}
+	}
+	var r io.Reader
+	if job.body != nil {
+		r = bytes.NewReader(job.body)
+	}
+	req, err := http.NewRequest(method, job.url, r)
+	if err != nil {
+		return nil, err
+	}
+	if job.contentType != "" {
+		req.Header.Set("Content-Type", job.contentType)
+	}
+	if rf.json != "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	for _, h := range job.headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, errors.New("invalid header " + h + ", expected \"Name: value\"")
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+		} else {
+			req.Header.Add(name, value)
+		}
+	}
+	for _, c := range rf.cookies {
+		for _, pair := range strings.Split(c, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return nil, errors.New("invalid cookie " + c + ", expected \"name=value\"")
+			}
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+	}
+	rf.authenticate(req)
+	return req, nil
+}
+
+// authenticate adds credentials from the flags or .netrc to req, unless it
+// already has an Authorization header.
+func (rf *requestFlags) authenticate(req *http.Request) {
+	if req.Header.Get("Authorization") != "" {
+		return
+	}
+	switch {
+	case rf.basic != "":
+		user, pass, _ := strings.Cut(rf.basic, ":")
+		req.SetBasicAuth(user, pass)
+	case rf.bearer != "":
+		req.Header.Set("Authorization", "Bearer "+rf.bearer)
+	case rf.netrc != nil && req.URL.User == nil:
+		login, ok := rf.netrc[req.URL.Hostname()]
+		if !ok {
+			login, ok = rf.netrc[""]
+		}
+		if ok {
+			req.SetBasicAuth(login.login, login.password)
+		}
+	}
+}
+
+// readArg returns the contents of the file named by an @file argument, or
+// the argument itself.
+func readArg(arg string) ([]byte, error) {
+	if strings.HasPrefix(arg, "@") {
+		return os.ReadFile(arg[1:])
+	}
+	return []byte(arg), nil
+}
+
+// multipartBody encodes name=value and name=@file fields as a multipart
+// form.
+func multipartBody(fields []string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, f := range fields {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, "", errors.New("invalid form field " + f + ", expected name=value")
+		}
+		if !strings.HasPrefix(value, "@") {
+			if err := mw.WriteField(name, value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(value[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		ctype := mime.TypeByExtension(filepath.Ext(value))
+		if ctype == "" {
+			ctype = http.DetectContentType(data)
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+			"name": name, "filename": filepath.Base(value[1:]),
+		}))
+		h.Set("Content-Type", ctype)
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err = w.Write(data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+// fetchCommand makes requests to the URLs given and logs the results to a
+// HAR file. It is the default when no other subcommand is given.
+func fetchCommand(args []string) error {
+	fs := flag.NewFlagSet("harhar", flag.ExitOnError)
+	output := fs.String("o", "results.har", "output har to `filename`, or - for stdout (gzipped if it ends in .gz)")
+	pretty := fs.Bool("pretty", false, "indent the output JSON")
+	concurrency := fs.Int("c", 1, "make up to `n` requests concurrently")
+	repeat := fs.Int("repeat", 1, "request each URL `k` times")
+	noFollow := fs.Bool("no-follow", false, "don't follow redirects (each hop is recorded either way)")
+	maxRedirects := fs.Int("max-redirects", 10, "follow at most `n` redirects")
+	timeout := fs.Duration("timeout", 0, "per-request `timeout`, e.g. 10s (default none)")
+	deadline := fs.Duration("deadline", 0, "overall `timeout` for all requests (default none)")
+	retries := fs.Int("retry", 0, "retry failed requests and 5xx or 429 responses up to `n` times")
+	backoff := fs.Duration("retry-delay", time.Second, "`delay` before the first retry, doubling after each")
+	jarFile := fs.String("cookie-jar", "", "load cookies from and save them to `file`, so they persist across requests and runs")
+	useNetrc := fs.Bool("netrc", false, "authenticate with credentials from ~/.netrc (or $NETRC)")
+	redactAuth := fs.Bool("redact-auth", false, "mask Authorization headers in the written HAR")
+	http11 := fs.Bool("http1.1", false, "only use HTTP/1.1")
+	http2 := fs.Bool("http2", false, "require HTTP/2 (over TLS)")
+	http3 := fs.Bool("http3", false, "use HTTP/3 (requires building with -tags quic)")
+	failStatus := fs.Bool("fail", false, "exit with an error if any response status is 400 or above (the HAR is still written)")
+	input := fs.String("f", "", "read URLs or \"METHOD URL [headerfile] [bodyfile]\" lines from `file`, or - for stdin")
+	var rf requestFlags
+	rf.register(fs)
+	var tf tlsFlags
+	tf.register(fs)
+	urls, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 && *input == "" {
+		return errors.New("usage: harhar [flags] [-o results.har] <URL> [<URL>...], see -h for flags")
+	}
+	if rf.basic != "" && rf.bearer != "" {
+		return errors.New("only one of --basic and --bearer may be used")
+	}
+	if *useNetrc {
+		if rf.netrc, err = readNetrc(); err != nil {
+			return err
+		}
+	}
+	if *concurrency < 1 || *repeat < 1 {
+		return errors.New("-c and --repeat must be at least 1")
+	}
+	defaults, err := rf.defaultJob()
+	if err != nil {
+		return err
+	}
+	jobs := make([]fetchJob, len(urls))
+	for i, u := range urls {
+		jobs[i] = defaults
+		jobs[i].url = u
+	}
+	if *input != "" {
+		in := os.Stdin
+		if *input != "-" {
+			if in, err = os.Open(*input); err != nil {
+				return err
+			}
+			defer in.Close()
+		}
+		more, err := readJobs(in, defaults)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *input, err)
+		}
+		jobs = append(jobs, more...)
+	}
+
+	tlsConfig, err := tf.config()
+	if err != nil {
+		return err
+	}
+	if (*http11 && *http2) || (*http11 && *http3) || (*http2 && *http3) {
+		return errors.New("only one of --http1.1, --http2 and --http3 may be used")
+	}
+	if *http3 && http3Transport == nil {
+		return errors.New("--http3 is not supported by this build, rebuild with -tags quic")
+	}
+
+	recorder := harhar.NewRecorder()
+	tport := recorder.RoundTripper.(*http.Transport)
+	tport.TLSClientConfig = tlsConfig
+	if *concurrency > 1 {
+		tport.MaxIdleConnsPerHost = *concurrency
+	}
+	recorder.RecordErrors = true
+	switch {
+	case *http11:
+		recorder.DisableHTTP2(true)
+	case *http3:
+		recorder.RoundTripper = http3Transport(tlsConfig)
+	}
+	if *redactAuth {
+		recorder.RedactHeaders = []string{"Authorization", "Proxy-Authorization"}
+		recorder.RedactMode = harhar.RedactMask
+	}
+	f := &fetcher{
+		client:  recorder.Client(!*noFollow),
+		rf:      &rf,
+		timeout: *timeout,
+		retries: *retries,
+		backoff: *backoff,
+
+		failStatus: *failStatus,
+	}
+	switch {
+	case *http11:
+		f.proto = "HTTP/1.1"
+	case *http2:
+		f.proto = "HTTP/2.0"
+	case *http3:
+		f.proto = "HTTP/3.0"
+	}
+	if !*noFollow {
+		f.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > *maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", *maxRedirects)
+			}
+			return nil
+		}
+	}
+	var jar *fileJar
+	if *jarFile != "" {
+		if jar, err = loadJar(*jarFile); err != nil {
+			return fmt.Errorf("%s: %w", *jarFile, err)
+		}
+		f.client.Jar = jar
+	}
+	ctx := context.Background()
+	if *deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *deadline)
+		defer cancel()
+	}
+	failed, unsent := f.fetchAll(ctx, jobs, *concurrency, *repeat)
+	if jar != nil {
+		if err = jar.Save(*jarFile); err != nil {
+			return err
+		}
+	}
+
+	// list entries in the order requests started, not completed, without
+	// SortOutput which would also reorder the headers of each
+	h := recorder.Snapshot()
+	h.SortEntries()
+	size, err := writeHAR(h, *output, *pretty)
+	if err != nil {
+		return err
+	}
+
+	// it's always good to report size when logging since memory usage
+	// will grow pretty quickly if you're not careful.
+	log.Printf("wrote %s (%.1fkb)\n", *output, float64(size)/1024.0)
+	if len(failed) > 0 || unsent > 0 {
+		if len(failed) > 0 {
+			log.Println("failed requests:")
+			for _, err := range failed {
+				log.Printf("  %v\n", err)
+			}
+		}
+		return fmt.Errorf("%d of %d requests failed", len(failed)+unsent, len(jobs)*(*repeat))
+	}
+	return nil
+}
+
+// http3Transport returns an HTTP/3 RoundTripper, if built with the quic tag
+// (see http3.go).
+var http3Transport func(*tls.Config) http.RoundTripper
+
+// fetcher makes requests for jobs, with timeouts and retries.
+type fetcher struct {
+	client  *http.Client
+	rf      *requestFlags
+	timeout time.Duration // per attempt, if non-zero
+	retries int
+	backoff time.Duration // before the first retry, doubling after each
+	proto   string        // required response protocol, if set
+
+	// failStatus treats responses with status 400 and above as failures
+	failStatus bool
+}
+
+// fetchAll makes each job's request repeat times, using up to concurrency
+// workers, and returns the errors of the requests which failed, and the
+// number of requests which were never made before ctx was done.
+func (f *fetcher) fetchAll(ctx context.Context, jobs []fetchJob, concurrency, repeat int) ([]error, int) {
+	work := make(chan fetchJob)
+	go func() {
+		defer close(work)
+		for _, job := range jobs {
+			for i := 0; i < repeat; i++ {
+				select {
+				case work <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		made   int
+		failed []error
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				err := f.fetch(ctx, job)
+				if err != nil {
+					log.Println(err)
+				}
+				mu.Lock()
+				made++
+				if err != nil {
+					failed = append(failed, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failed, len(jobs)*repeat - made
+}
+
+// fetch makes the request for a single job, retrying server errors and
+// failed requests. Each attempt is recorded, and retries are tagged "retry".
+func (f *fetcher) fetch(ctx context.Context, job fetchJob) error {
+	delay := f.backoff
+	for attempt := 0; ; attempt++ {
+		actx := ctx
+		if attempt > 0 {
+			actx = harhar.WithTags(ctx, "retry")
+		}
+		code, status, retry, err := f.attempt(actx, job)
+		if err == nil {
+			log.Printf("got %s from %s\n", status, job.url)
+		}
+		if !retry || attempt >= f.retries || ctx.Err() != nil {
+			if err == nil && f.failStatus && code >= 400 {
+				err = fmt.Errorf("%s: got %s", job.url, status)
+			}
+			return err
+		}
+		if err != nil {
+			log.Printf("retrying %s in %s: %v\n", job.url, delay, err)
+		} else {
+			log.Printf("retrying %s in %s: got %s\n", job.url, delay, status)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// attempt makes a single request for job, returning the response status and
+// whether it is worth retrying.
+func (f *fetcher) attempt(ctx context.Context, job fetchJob) (int, string, bool, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+	req, err := f.rf.newRequest(job)
+	if err != nil {
+		return 0, "", false, err
+	}
+	resp, err := f.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, "", true, err
+	}
+	resp.Body.Close()
+	if f.proto != "" && resp.Proto != f.proto {
+		return 0, "", false, fmt.Errorf("%s: server responded with %s, not %s", job.url, resp.Proto, f.proto)
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return resp.StatusCode, resp.Status, retry, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/pbnjay/harhar"
+)
+
+func filterCommand(args []string) error {
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	output := fs.String("o", "", "output `filename` (default stdout)")
+	pathRE := fs.String("path", "", "only include request paths matching `regexp`")
+	since := fs.String("since", "", "only include entries started at or after `time` (RFC 3339)")
+	until := fs.String("until", "", "only include entries started before `time` (RFC 3339)")
+	var opts harhar.FilterOptions
+	fs.Var((*stringsFlag)(&opts.Hosts), "host", "only include requests to hosts matching `glob` (repeatable)")
+	fs.Var((*stringsFlag)(&opts.Methods), "method", "only include requests with `method` (repeatable)")
+	fs.Var((*stringsFlag)(&opts.Statuses), "status", "only include responses with `status`, e.g. 404 or 5xx (repeatable)")
+	fs.Var((*stringsFlag)(&opts.MIMETypes), "mime", "only include responses with MIME type matching `glob` (repeatable)")
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar filter <input.har> [--host glob] [--path regexp] [--method M] [--status 5xx] [--mime glob] [--since t] [--until t] [-o output.har]")
+	}
+
+	if *pathRE != "" {
+		if opts.Path, err = regexp.Compile(*pathRE); err != nil {
+			return err
+		}
+	}
+	if *since != "" {
+		if opts.Since, err = time.Parse(time.RFC3339, *since); err != nil {
+			return err
+		}
+	}
+	if *until != "" {
+		if opts.Until, err = time.Parse(time.RFC3339, *until); err != nil {
+			return err
+		}
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	kept, total, err := filterTo(out, files[0], opts)
+	if err != nil || out == os.Stdout {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	st, err := os.Stat(*output)
+	if err != nil {
+		return err
+	}
+	log.Printf("wrote %s (%d of %d entries, %.1fkb)\n", *output, kept, total, float64(st.Size())/1024.0)
+	return nil
+}
+
+// filterTo writes the entries of a HAR file which are matched by opts to w,
+// and returns the number of entries kept and in total. If the file has an up
+// to date sidecar index, only the entries it selects are decoded, otherwise
+// the file is streamed one entry at a time.
+func filterTo(w io.Writer, filename string, opts harhar.FilterOptions) (int, int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	ix, err := harhar.ReadIndex(filename)
+	if err != nil {
+		return harhar.FilterStream(f, w, opts)
+	}
+
+	h, err := ix.ReadLog(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = eachIndexed(f, ix, opts, func(i int, ent *harhar.Entry) error {
+		h.Log.Entries = append(h.Log.Entries, *ent)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	pages := make(map[string]bool)
+	for _, ent := range h.Log.Entries {
+		pages[ent.PageRef] = true
+	}
+	kept := h.Log.Pages[:0]
+	for _, pg := range h.Log.Pages {
+		if pages[pg.ID] {
+			kept = append(kept, pg)
+		}
+	}
+	h.Log.Pages = kept
+	_, err = h.WriteTo(w)
+	return len(h.Log.Entries), len(ix.Entries), err
+}
+
+// eachEntry calls fn with each entry of a HAR file matched by opts, and its
+// position in the file. If the file has an up to date sidecar index, only
+// the entries it selects are decoded, otherwise the file is streamed one
+// entry at a time.
+func eachEntry(filename string, opts harhar.FilterOptions, fn func(i int, ent *harhar.Entry) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if ix, err := harhar.ReadIndex(filename); err == nil {
+		return eachIndexed(f, ix, opts, fn)
+	}
+	i := -1
+	return harhar.Stream(f, func(ent harhar.Entry) error {
+		i++
+		if !opts.Match(&ent) {
+			return nil
+		}
+		return fn(i, &ent)
+	})
+}
+
+// eachIndexed calls fn with each entry of the HAR file contents r selected
+// by the index and matched by opts.
+func eachIndexed(r io.ReaderAt, ix *harhar.Index, opts harhar.FilterOptions, fn func(i int, ent *harhar.Entry) error) error {
+	for _, i := range ix.Select(opts) {
+		ent, err := ix.ReadEntry(r, i)
+		if err != nil {
+			return err
+		}
+		if !opts.Match(&ent) {
+			continue
+		}
+		if err = fn(i, &ent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
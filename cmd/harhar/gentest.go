@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/pbnjay/harhar"
+)
+
+func genTestCommand(args []string) error {
+	fs := flag.NewFlagSet("gen-test", flag.ExitOnError)
+	output := fs.String("o", "", "output `filename` (default stdout)")
+	pkg := fs.String("package", "main", "`name` of the generated test's package")
+	var indexes stringsFlag
+	fs.Var(&indexes, "i", "only generate a test for the entry at `index` (repeatable)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar gen-test <results.har> [-i index] [--package name] [-o replay_test.go]")
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	var selected []int
+	for _, s := range indexes {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid entry index %q", s)
+		}
+		selected = append(selected, i)
+	}
+
+	if *output == "" {
+		return h.WriteGoTest(os.Stdout, *pkg, selected...)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = h.WriteGoTest(f, *pkg, selected...); err != nil {
+		return err
+	}
+	log.Printf("wrote %s\n", *output)
+	return f.Close()
+}
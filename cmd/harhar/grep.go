@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pbnjay/harhar"
+)
+
+func grepCommand(args []string) error {
+	fs := flag.NewFlagSet("grep", flag.ExitOnError)
+	fixed := fs.Bool("F", false, "treat the pattern as a literal string")
+	ignoreCase := fs.Bool("i", false, "match case-insensitively")
+	in := fs.String("in", "url,headers,bodies", "comma-separated `parts` to search: url, headers, bodies")
+	context := fs.Int("C", 40, "show up to `n` bytes of context around matches")
+	list := fs.Bool("l", false, "only list the indexes of matching entries")
+	pathRE := fs.String("path", "", "only search request paths matching `regexp`")
+	var opts harhar.FilterOptions
+	fs.Var((*stringsFlag)(&opts.Hosts), "host", "only search requests to hosts matching `glob` (repeatable)")
+	fs.Var((*stringsFlag)(&opts.Statuses), "status", "only search responses with `status`, e.g. 404 or 5xx (repeatable)")
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return errors.New("usage: harhar grep <pattern> <results.har> [-F] [-i] [--in url,headers,bodies] [-C n] [-l] [--host glob] [--path regexp] [--status 5xx] [--tag t]")
+	}
+	if *pathRE != "" {
+		if opts.Path, err = regexp.Compile(*pathRE); err != nil {
+			return err
+		}
+	}
+
+	var scope harhar.GrepScope
+	for _, part := range strings.Split(*in, ",") {
+		switch strings.TrimSpace(part) {
+		case "url":
+			scope |= harhar.GrepURL
+		case "headers":
+			scope |= harhar.GrepHeaders
+		case "bodies":
+			scope |= harhar.GrepBodies
+		default:
+			return fmt.Errorf("unknown --in part %q", part)
+		}
+	}
+	pattern := files[0]
+	if *fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if *ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	// entries are numbered by their position in the file
+	found := false
+	err = eachEntry(files[1], opts, func(i int, ent *harhar.Entry) error {
+		matches := harhar.GrepEntry(i, ent, re, scope, *context)
+		if len(matches) == 0 {
+			return nil
+		}
+		found = true
+		if *list {
+			fmt.Println(i)
+			return nil
+		}
+		fmt.Printf("#%d %s %s -> %d\n", i, ent.Request.Method, ent.Request.URL, ent.Response.StatusCode)
+		for _, m := range matches {
+			fmt.Printf("    %s: %s\n", m.Field, m.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("no matches")
+	}
+	return nil
+}
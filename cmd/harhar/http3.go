@@ -0,0 +1,22 @@
+//go:build quic
+
+// HTTP/3 support requires quic-go, which is not a dependency of the default
+// build. To enable --http3:
+//
+//	go get github.com/quic-go/quic-go
+//	go build -tags quic ./cmd/harhar
+
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func init() {
+	http3Transport = func(cfg *tls.Config) http.RoundTripper {
+		return &http3.Transport{TLSClientConfig: cfg}
+	}
+}
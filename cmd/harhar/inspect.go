@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/harhar"
+)
+
+func inspectCommand(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	maxBody := fs.Int("max-body", 4096, "truncate bodies longer than `n` bytes (0 for no limit)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) < 1 || len(files) > 2 {
+		return errors.New("usage: harhar inspect <results.har> [index|url] [--max-body n]")
+	}
+
+	if len(files) == 2 {
+		if i, ent, ok := findIndexed(files[0], files[1]); ok {
+			printEntry(os.Stdout, i, &ent, *maxBody)
+			return nil
+		}
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	if len(files) == 1 {
+		// list the entries to choose from
+		for i, ent := range h.Log.Entries {
+			fmt.Printf("#%d %s %s -> %d\n", i, ent.Request.Method, ent.Request.URL, ent.Response.StatusCode)
+		}
+		return nil
+	}
+
+	sel := files[1]
+	i, err := strconv.Atoi(sel)
+	if err != nil {
+		// the first entry with the URL, or containing it
+		i = -1
+		for j, ent := range h.Log.Entries {
+			if ent.Request.URL == sel {
+				i = j
+				break
+			}
+			if i < 0 && strings.Contains(ent.Request.URL, sel) {
+				i = j
+			}
+		}
+	}
+	if i < 0 || i >= len(h.Log.Entries) {
+		return fmt.Errorf("no entry %q", sel)
+	}
+	printEntry(os.Stdout, i, &h.Log.Entries[i], *maxBody)
+	return nil
+}
+
+// findIndexed uses the sidecar index of a HAR file, if there is an up to
+// date one, to decode only the entry at the index or with the exact URL sel.
+// ok is false if the index can't be used, e.g. to search for part of a URL.
+func findIndexed(filename, sel string) (int, harhar.Entry, bool) {
+	ix, err := harhar.ReadIndex(filename)
+	if err != nil {
+		return 0, harhar.Entry{}, false
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, harhar.Entry{}, false
+	}
+	defer f.Close()
+
+	if i, err := strconv.Atoi(sel); err == nil {
+		if i < 0 || i >= len(ix.Entries) {
+			return 0, harhar.Entry{}, false
+		}
+		ent, err := ix.ReadEntry(f, i)
+		return i, ent, err == nil
+	}
+	u, err := url.Parse(sel)
+	if err != nil || u.Host == "" {
+		return 0, harhar.Entry{}, false
+	}
+	opts := harhar.FilterOptions{
+		Hosts: []string{u.Hostname()},
+		Path:  regexp.MustCompile("^" + regexp.QuoteMeta(u.Path) + "$"),
+	}
+	for _, i := range ix.Select(opts) {
+		ent, err := ix.ReadEntry(f, i)
+		if err != nil {
+			return 0, harhar.Entry{}, false
+		}
+		if ent.Request.URL == sel {
+			return i, ent, true
+		}
+	}
+	return 0, harhar.Entry{}, false
+}
+
+// printEntry writes a human-readable view of the entry to w.
+func printEntry(w io.Writer, i int, ent *harhar.Entry, maxBody int) {
+	req, resp := &ent.Request, &ent.Response
+	fmt.Fprintf(w, "#%d started %s, took %dms\n", i, ent.Start, ent.Time)
+	if ent.Error != "" {
+		fmt.Fprintf(w, "error: %s\n", ent.Error)
+	}
+	if len(ent.Tags) > 0 {
+		fmt.Fprintf(w, "tags: %s\n", strings.Join(ent.Tags, ", "))
+	}
+	fmt.Fprintln(w)
+
+	target := req.URL
+	if u, err := url.Parse(req.URL); err == nil {
+		target = u.RequestURI()
+		if !hasHeader(req.Headers, "Host") && !hasHeader(req.Headers, ":authority") {
+			fmt.Fprintf(w, "> %s %s %s\n> Host: %s\n", req.Method, target, req.HTTPVersion, u.Host)
+			target = ""
+		}
+	}
+	if target != "" {
+		fmt.Fprintf(w, "> %s %s %s\n", req.Method, target, req.HTTPVersion)
+	}
+	for _, p := range req.Headers {
+		fmt.Fprintf(w, "> %s: %s\n", p.Name, p.Value)
+	}
+	fmt.Fprintln(w, ">")
+	switch {
+	case len(req.Body.Params) > 0:
+		for _, p := range req.Body.Params {
+			if p.FileName != "" {
+				fmt.Fprintf(w, "  %s: [file %s, %s]\n", p.Name, p.FileName, p.ContentType)
+			} else {
+				fmt.Fprintf(w, "  %s=%s\n", p.Name, p.Value)
+			}
+		}
+		fmt.Fprintln(w)
+	case req.Body.FileRef != "":
+		fmt.Fprintf(w, "[body stored in %s]\n\n", req.Body.FileRef)
+	default:
+		printBody(w, req.Body.MIMEType, req.Body.Text(), req.Body.Encoding, maxBody)
+	}
+
+	if resp.StatusCode > 0 {
+		fmt.Fprintf(w, "< %s %d %s\n", resp.HTTPVersion, resp.StatusCode, resp.StatusText)
+		for _, p := range resp.Headers {
+			fmt.Fprintf(w, "< %s: %s\n", p.Name, p.Value)
+		}
+		fmt.Fprintln(w, "<")
+		if resp.Body.FileRef != "" {
+			fmt.Fprintf(w, "[body stored in %s]\n\n", resp.Body.FileRef)
+		} else {
+			printBody(w, resp.Body.MIMEType, resp.Body.Text(), resp.Body.Encoding, maxBody)
+		}
+	}
+
+	t := ent.Timings
+	fmt.Fprintln(w, "timings:")
+	for _, phase := range []struct {
+		name string
+		ms   int
+	}{
+		{"blocked", t.Blocked}, {"dns", t.DNS}, {"connect", t.Connect}, {"ssl", t.SSL},
+		{"send", t.Send}, {"wait", t.Wait}, {"receive", t.Receive},
+	} {
+		if phase.ms > 0 {
+			fmt.Fprintf(w, "  %-8s %6dms\n", phase.name, phase.ms)
+		}
+	}
+	fmt.Fprintf(w, "  %-8s %6dms\n", "total", ent.Time)
+}
+
+// printBody writes a body, pretty-printing JSON and summarizing binary data.
+func printBody(w io.Writer, mimeType, text, encoding string, maxBody int) {
+	if text == "" {
+		return
+	}
+	if encoding == "base64" {
+		fmt.Fprintf(w, "[%d bytes of base64-encoded %s]\n\n", len(text), mimeType)
+		return
+	}
+	if strings.Contains(mimeType, "json") || json.Valid([]byte(text)) {
+		var buf bytes.Buffer
+		if json.Indent(&buf, []byte(text), "", "  ") == nil {
+			text = buf.String()
+		}
+	}
+	if maxBody > 0 && len(text) > maxBody {
+		text = text[:maxBody] + fmt.Sprintf("\n[... %d more bytes]", len(text)-maxBody)
+	}
+	fmt.Fprintf(w, "%s\n\n", strings.TrimRight(text, "\n"))
+}
+
+func hasHeader(pairs []harhar.NameValuePair, name string) bool {
+	for _, p := range pairs {
+		if strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
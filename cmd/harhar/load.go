@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/pbnjay/harhar"
+)
+
+func loadCommand(args []string) error {
+	fs := flag.NewFlagSet("load", flag.ExitOnError)
+	concurrency := fs.Int("c", 10, "number of concurrent `workers`")
+	duration := fs.Duration("d", 30*time.Second, "test `duration`, or 0 to run until interrupted")
+	target := fs.String("target", "", "send requests to `url` (scheme and host) instead of the recorded hosts")
+	output := fs.String("o", "", "write a sample of the generated traffic to `filename`")
+	sample := fs.Int("sample", 100, "record 1 in every `n` requests to the -o sample")
+	var tags stringsFlag
+	fs.Var(&tags, "tag", "only replay entries with `tag` (repeatable)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar load <results.har> [-c 10] [-d 30s] [--target url] [-o sample.har] [--sample 100]")
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	runner := harhar.NewReplayRunner(h)
+	runner.Tags = tags
+	if *target != "" {
+		if runner.Target, err = url.Parse(*target); err != nil {
+			return err
+		}
+		if runner.Target.Scheme == "" || runner.Target.Host == "" {
+			return fmt.Errorf("invalid --target %q, expected scheme://host", *target)
+		}
+	}
+	if *output == "" {
+		runner.Recorder.Pause()
+	} else {
+		runner.Recorder.Sampler = harhar.SampleEvery(*sample)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	log.Printf("replaying %d entries with %d workers for %s\n", len(h.Log.Entries), *concurrency, *duration)
+	res, err := runner.Load(ctx, *concurrency, *duration)
+	if err != nil {
+		return err
+	}
+
+	secs := res.Elapsed.Seconds()
+	fmt.Printf("requests:   %d in %.1fs (%.1f/s)\n", res.Count, secs, float64(res.Count)/secs)
+	fmt.Printf("received:   %.1fkb\n", float64(res.Bytes)/1024.0)
+	fmt.Printf("latency:    p50 %dms, p95 %dms, p99 %dms, max %dms\n", res.P50, res.P95, res.P99, res.Max)
+
+	codes := make([]int, 0, len(res.ByStatus))
+	for code := range res.ByStatus {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Printf("status %d: %d\n", code, res.ByStatus[code])
+	}
+	msgs := make([]string, 0, len(res.Errors))
+	for msg := range res.Errors {
+		msgs = append(msgs, msg)
+	}
+	sort.Strings(msgs)
+	for _, msg := range msgs {
+		fmt.Printf("error (%d): %s\n", res.Errors[msg], msg)
+	}
+
+	if *output != "" {
+		size, err := runner.Recorder.WriteFile(*output)
+		if err != nil {
+			return err
+		}
+		log.Printf("wrote %s (%.1fkb)\n", *output, float64(size)/1024.0)
+	}
+	return nil
+}
@@ -1,42 +1,191 @@
-// Command harhar will do GET requests on provided URLs and log the results to a HAR file.
+// Command harhar will do requests on provided URLs and log the results to a HAR file.
 // This is a simple example that concisely showcases all the features and usage.
 //
-//		 USAGE: ./harhar [-o results.har] <URL> [<URL>...]
+//		 USAGE: ./harhar [flags] [-o results.har|results.har.gz|-] [--pretty] <URL> [<URL>...]
 //	   ex: ./harhar https://google.com https://yahoo.com https://bing.com
+//	   ex: ./harhar -X PUT -H "Authorization: Bearer x" --json '{"a":1}' https://example.com/api
+//
+// Flags control the requests made:
+//
+//	request:  -X method, -H "Name: value", -d data, --data-file f, --json data, --form name=value
+//	input:    -f requests.txt (or - for stdin), -c workers, --repeat k
+//	redirect: --no-follow, --max-redirects n, --timeout d, --deadline d, --retry n, --retry-delay d
+//	status:   --fail to exit non-zero if any response is 400 or above
+//	cookies:  --cookie-jar jar.json, --cookie name=value
+//	auth:     --basic user:pass, --bearer token, --netrc, --redact-auth
+//	tls:      --insecure, --cacert f, --cert f --key f, --tls-min v, --tls-max v
+//	protocol: --http1.1, --http2, --http3 (when built with -tags quic, see http3.go)
+//
+// The same is available as "./harhar fetch ..." for URLs which look like subcommands.
+//
+// Additional subcommands operate on existing HAR files:
+//
+//	./harhar split big.har --every 5m [-o prefix] [--tag t]
+//	./harhar compact in.har -o out.har [--strip-bodies] [--dedupe] [--sort-fields] [--gzip] [--tag t]
+//	./harhar merge a.har b.har [...] -o out.har [--dedupe]
+//	./harhar diff old.har new.har [--format text|json] [--latency ms]
+//	./harhar filter in.har [--host glob] [--path regexp] [--method M] [--status 5xx] [--mime glob] [-o out.har]
+//	./harhar scrub in.har [--header glob] [--mask regexp] [--strip-bodies] [--rewrite-host old=new] [-o out.har]
+//	./harhar inspect results.har [index|url] [--max-body n]
+//	./harhar stats results.har [--tag t]
+//	./harhar top results.har [-n 10] [--sort total|count|max|p50|p95|p99] [--tag t]
+//	./harhar grep <pattern> results.har [-F] [-i] [--in url,headers,bodies] [-C n] [-l] [--host glob] [--path regexp] [--status 5xx] [--tag t]
+//	./harhar report results.har [-o report.html] [--tag t]
+//	./harhar waterfall results.har [--width 60] [--no-color] [--tag t]
+//	./harhar replay results.har [--target https://staging.example.com] [--speed 1 | --max-throughput] [--assert] [-o replayed.har]
+//	./harhar load results.har [-c 10] [-d 30s] [--target url] [-o sample.har] [--sample 100]
+//	./harhar to-curl results.har [-i index] [--tag t]
+//	./harhar gen-test results.har [-i index] [--package name] [-o replay_test.go]
+//	./harhar export results.har --format postman|openapi|ndjson|csv|k6|vegeta [-o output] [--tag t]
+//	./harhar schema [-o har.schema.json]
+//	./harhar validate-spec results.har --spec api.json
+//	./harhar anomalies results.har [--threshold 3.5]
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"flag"
+	"io"
 	"log"
-	"net/http"
+	"os"
+	"strings"
 
 	"github.com/pbnjay/harhar"
 )
 
+// subcommands, most of which operate on existing HAR files
+var commands = map[string]func(args []string) error{
+	"fetch":         fetchCommand,
+	"split":         splitCommand,
+	"compact":       compactCommand,
+	"merge":         mergeCommand,
+	"diff":          diffCommand,
+	"filter":        filterCommand,
+	"scrub":         scrubCommand,
+	"inspect":       inspectCommand,
+	"stats":         statsCommand,
+	"top":           topCommand,
+	"grep":          grepCommand,
+	"export":        exportCommand,
+	"gen-test":      genTestCommand,
+	"report":        reportCommand,
+	"waterfall":     waterfallCommand,
+	"schema":        schemaCommand,
+	"anomalies":     anomaliesCommand,
+	"replay":        replayCommand,
+	"load":          loadCommand,
+	"to-curl":       toCurlCommand,
+	"validate-spec": validateSpecCommand,
+}
+
 func main() {
-	var (
-		output = flag.String("o", "results.har", "output har to `filename`")
-	)
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
 
-	flag.Parse()
+	if err := fetchCommand(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	recorder := harhar.NewRecorder()
-	client := &http.Client{Transport: recorder}
+// writeHAR writes h to filename, or to stdout if filename is "-". Output is
+// gzipped if filename ends in ".gz", and indented if pretty is set. Returns the
+// number of bytes written before compression.
+func writeHAR(h *harhar.HAR, filename string, pretty bool) (int64, error) {
+	f := os.Stdout
+	if filename != "-" {
+		var err error
+		if f, err = os.Create(filename); err != nil {
+			return 0, err
+		}
+		defer f.Close()
+	}
+	bw := bufio.NewWriter(f)
+	var w io.Writer = bw
+	var zw *gzip.Writer
+	if strings.HasSuffix(filename, ".gz") {
+		zw = gzip.NewWriter(bw)
+		w = zw
+	}
 
-	for _, u := range flag.Args() {
-		resp, err := client.Get(u)
-		if err != nil {
-			log.Fatal(err)
+	var n int64
+	var err error
+	if pretty {
+		var buf, out bytes.Buffer
+		if _, err = h.WriteTo(&buf); err != nil {
+			return 0, err
 		}
-		log.Printf("got %s from %s\n", resp.Status, u)
+		if err = json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return 0, err
+		}
+		out.WriteByte('\n')
+		n, err = out.WriteTo(w)
+	} else {
+		n, err = h.WriteTo(w)
+	}
+	if err == nil && zw != nil {
+		err = zw.Close()
 	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil && f != os.Stdout {
+		err = f.Close()
+	}
+	return n, err
+}
 
-	size, err := recorder.WriteFile(*output)
-	if err != nil {
-		log.Fatal(err)
+// parseArgs parses flags which may appear before, after, or between the
+// positional arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		if args[0] == "--" {
+			return append(pos, args[1:]...), nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// tagFlag adds a repeatable --tag flag to fs, selecting entries with any of
+// the tags given (see harhar.FilterOptions.Tags).
+func tagFlag(fs *flag.FlagSet, opts *harhar.FilterOptions) {
+	fs.Var((*stringsFlag)(&opts.Tags), "tag", "only include entries with `tag` (repeatable)")
+}
+
+// readSelected reads a HAR file, keeping only the entries matched by opts.
+func readSelected(filename string, opts harhar.FilterOptions) (*harhar.HAR, error) {
+	h, err := harhar.ReadFile(filename)
+	if err != nil || len(opts.Tags) == 0 {
+		return h, err
 	}
+	return harhar.Filter(h, opts), nil
+}
+
+// stringsFlag collects the values of a repeated string flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
 
-	// it's always good to report size when logging since memory usage
-	// will grow pretty quickly if you're not careful.
-	log.Printf("wrote %s (%.1fkb)\n", *output, float64(size)/1024.0)
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
 }
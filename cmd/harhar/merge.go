@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"path/filepath"
+
+	"github.com/pbnjay/harhar"
+)
+
+func mergeCommand(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := fs.String("o", "", "output `filename` (required)")
+	dedupe := fs.Bool("dedupe", false, "drop entries duplicated between inputs")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) < 2 || *output == "" {
+		return errors.New("usage: harhar merge <a.har> <b.har> [...] -o <output.har> [--dedupe]")
+	}
+
+	opts := harhar.MergeOptions{Dedupe: *dedupe}
+	hars := make([]*harhar.HAR, len(files))
+	for i, fn := range files {
+		hars[i], err = harhar.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		opts.Sources = append(opts.Sources, filepath.Base(fn))
+	}
+
+	h, conflicts := harhar.MergeWithOptions(opts, hars...)
+	for _, c := range conflicts {
+		switch c.Kind {
+		case harhar.ConflictDuplicate:
+			log.Printf("duplicate in %s and %s (%s apart): %s %s\n", c.Sources[0], c.Sources[1], c.Offset, c.Method, c.URL)
+		case harhar.ConflictClockSkew:
+			log.Printf("clocks of %s and %s appear to differ by %s\n", c.Sources[0], c.Sources[1], c.Offset)
+		}
+	}
+
+	size, err := h.WriteFile(*output)
+	if err != nil {
+		return err
+	}
+	log.Printf("wrote %s (%d entries, %.1fkb)\n", *output, len(h.Log.Entries), float64(size)/1024.0)
+	return nil
+}
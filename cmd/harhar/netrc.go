@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// netrcLogin is a login from a .netrc file.
+type netrcLogin struct {
+	login, password string
+}
+
+// readNetrc parses the logins in the .netrc file named by $NETRC, or
+// ~/.netrc by default. The "default" entry is stored under the empty name.
+func readNetrc() (map[string]netrcLogin, error) {
+	filename := os.Getenv("NETRC")
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		filename = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseNetrc(string(data)), nil
+}
+
+func parseNetrc(data string) map[string]netrcLogin {
+	logins := make(map[string]netrcLogin)
+	var machine string
+	var cur *netrcLogin
+	save := func() {
+		if cur != nil {
+			logins[machine] = *cur
+		}
+	}
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			next := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				save()
+				machine, cur = next(), &netrcLogin{}
+			case "default":
+				save()
+				machine, cur = "", &netrcLogin{}
+			case "login":
+				if cur != nil {
+					cur.login = next()
+				}
+			case "password":
+				if cur != nil {
+					cur.password = next()
+				}
+			case "account":
+				next()
+			case "macdef":
+				// macro definitions run until the next blank line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	save()
+	return logins
+}
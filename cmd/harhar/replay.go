@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/pbnjay/harhar"
+)
+
+func replayCommand(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	output := fs.String("o", "replayed.har", "output har to `filename`")
+	target := fs.String("target", "", "send requests to `url` (scheme and host) instead of the recorded hosts")
+	speed := fs.Float64("speed", 1, "reproduce the recorded gaps between requests, scaled by `factor`")
+	maxThroughput := fs.Bool("max-throughput", false, "send requests back-to-back, ignoring the recorded timing")
+	bodyDir := fs.String("body-dir", "", "load externally stored bodies from `dir`")
+	assert := fs.Bool("assert", false, "compare replayed responses to the recorded ones, and fail if any differ")
+	ignoreBody := fs.Bool("ignore-body", false, "don't compare response bodies in --assert mode")
+	var tags, assertHeaders, ignorePaths stringsFlag
+	fs.Var(&tags, "tag", "only replay entries with `tag` (repeatable)")
+	fs.Var(&assertHeaders, "assert-header", "also compare response header `name` in --assert mode (repeatable)")
+	fs.Var(&ignorePaths, "ignore-path", "don't compare JSON body field `path`, e.g. data.*.id (repeatable)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar replay <results.har> [--target https://staging.example.com] [--speed 1 | --max-throughput] [--assert] [--tag t] [-o replayed.har]")
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	if *bodyDir != "" {
+		if err = h.LoadBodies(*bodyDir); err != nil {
+			return err
+		}
+	}
+	runner := harhar.NewReplayRunner(h)
+	runner.Tags = tags
+	runner.Speed = *speed
+	if *maxThroughput {
+		runner.Speed = 0
+	} else if *speed <= 0 {
+		return fmt.Errorf("invalid --speed %v, must be positive", *speed)
+	}
+	if *target != "" {
+		if runner.Target, err = url.Parse(*target); err != nil {
+			return err
+		}
+		if runner.Target.Scheme == "" || runner.Target.Host == "" {
+			return fmt.Errorf("invalid --target %q, expected scheme://host", *target)
+		}
+	}
+
+	if *assert {
+		runner.Assert = &harhar.AssertOptions{
+			Headers:     assertHeaders,
+			IgnorePaths: ignorePaths,
+			IgnoreBody:  *ignoreBody,
+		}
+	}
+
+	results, err := runner.Run(context.Background())
+	failed, mismatched := 0, 0
+	for _, res := range results {
+		rec := res.Recorded
+		switch {
+		case res.Err != nil:
+			failed++
+			log.Printf("#%d %s %s: %v\n", res.Index, rec.Request.Method, rec.Request.URL, res.Err)
+		case res.Replayed != nil:
+			log.Printf("#%d %s %s -> %d (%dms, was %d in %dms)\n", res.Index, rec.Request.Method, res.Replayed.Request.URL,
+				res.Replayed.Response.StatusCode, res.Replayed.Time, rec.Response.StatusCode, rec.Time)
+			if len(res.Mismatches) > 0 {
+				mismatched++
+			}
+			for _, m := range res.Mismatches {
+				log.Printf("    %s\n", m)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	size, err := runner.Recorder.WriteFile(*output)
+	if err != nil {
+		return err
+	}
+	log.Printf("wrote %s (%d of %d requests replayed, %.1fkb)\n", *output, len(results)-failed, len(results), float64(size)/1024.0)
+	if *assert && (mismatched > 0 || failed > 0) {
+		return fmt.Errorf("%d of %d responses differ from the recording, %d requests failed", mismatched, len(results), failed)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/pbnjay/harhar"
+)
+
+func reportCommand(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	output := fs.String("o", "report.html", "output `filename`")
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar report <results.har> [-o report.html] [--tag t]")
+	}
+
+	h, err := readSelected(files[0], opts)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = h.WriteHTML(f, filepath.Base(files[0])); err != nil {
+		return err
+	}
+	log.Printf("wrote %s (%d entries)\n", *output, len(h.Log.Entries))
+	return f.Close()
+}
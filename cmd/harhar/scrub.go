@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pbnjay/harhar"
+)
+
+func scrubCommand(args []string) error {
+	fs := flag.NewFlagSet("scrub", flag.ExitOnError)
+	output := fs.String("o", "", "output `filename` (default stdout)")
+	strip := fs.Bool("strip-bodies", false, "remove request and response body content")
+	var headers, masks, hosts stringsFlag
+	fs.Var(&headers, "header", "remove headers matching `glob` (repeatable, default "+strings.Join(harhar.DefaultScrubHeaders, ",")+")")
+	fs.Var(&masks, "mask", "replace text matching `regexp` with [REDACTED] (repeatable)")
+	fs.Var(&hosts, "rewrite-host", "replace host name `old=new` (repeatable)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar scrub <input.har> [--header glob] [--mask regexp] [--strip-bodies] [--rewrite-host old=new] [-o output.har]")
+	}
+
+	opts := harhar.ScrubOptions{
+		Headers:     headers,
+		StripBodies: *strip,
+	}
+	if len(opts.Headers) == 0 {
+		opts.Headers = harhar.DefaultScrubHeaders
+	}
+	for _, m := range masks {
+		re, err := regexp.Compile(m)
+		if err != nil {
+			return err
+		}
+		opts.Mask = append(opts.Mask, re)
+	}
+	if len(hosts) > 0 {
+		opts.RewriteHosts = make(map[string]string)
+		for _, hs := range hosts {
+			from, to, ok := strings.Cut(hs, "=")
+			if !ok || from == "" {
+				return fmt.Errorf("invalid --rewrite-host %q, expected old=new", hs)
+			}
+			opts.RewriteHosts[from] = to
+		}
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	h.Scrub(opts)
+	if *output == "" {
+		_, err = h.WriteTo(os.Stdout)
+		return err
+	}
+	size, err := h.WriteFile(*output)
+	if err != nil {
+		return err
+	}
+	log.Printf("wrote %s (%d entries, %.1fkb)\n", *output, len(h.Log.Entries), float64(size)/1024.0)
+	return nil
+}
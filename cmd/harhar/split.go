@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/harhar"
+)
+
+func splitCommand(args []string) error {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	every := fs.Duration("every", 5*time.Minute, "split into archives covering `duration` each")
+	prefix := fs.String("o", "", "output `prefix` for split archives (default input name)")
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar split <input.har> [--every 5m] [-o prefix] [--tag t]")
+	}
+
+	h, err := readSelected(files[0], opts)
+	if err != nil {
+		return err
+	}
+	if *prefix == "" {
+		*prefix = strings.TrimSuffix(files[0], filepath.Ext(files[0]))
+	}
+
+	for _, part := range h.Split(*every) {
+		name := *prefix + "-undated.har"
+		if len(part.Log.Entries) > 0 {
+			if t := part.Log.Entries[0].StartTime(); !t.IsZero() {
+				name = fmt.Sprintf("%s-%s.har", *prefix, t.Truncate(*every).Format("20060102T150405"))
+			}
+		}
+		size, err := part.WriteFile(name)
+		if err != nil {
+			return err
+		}
+		log.Printf("wrote %s (%d entries, %.1fkb)\n", name, len(part.Log.Entries), float64(size)/1024.0)
+	}
+	return nil
+}
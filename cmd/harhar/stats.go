@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pbnjay/harhar"
+)
+
+func statsCommand(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar stats <results.har> [--tag t]")
+	}
+
+	// streamed, for archives too large to load
+	f, err := os.Open(files[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sum, err := harhar.SummarizeStream(f, opts)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	printStats(tw, "", map[string]*harhar.Stats{"TOTAL": &sum.Stats})
+	printStats(tw, "HOST", sum.ByHost)
+	printStats(tw, "ENDPOINT", sum.ByEndpoint)
+	tw.Flush()
+
+	codes := make([]int, 0, len(sum.ByStatus))
+	for code := range sum.ByStatus {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Println("\nSTATUS")
+	for _, code := range codes {
+		fmt.Printf("  %d\t%d\n", code, sum.ByStatus[code])
+	}
+
+	if len(sum.ByTag) > 0 {
+		tags := make([]string, 0, len(sum.ByTag))
+		for t := range sum.ByTag {
+			tags = append(tags, t)
+		}
+		sort.Strings(tags)
+		fmt.Println("\nTAG")
+		for _, t := range tags {
+			fmt.Printf("  %s\t%d\n", t, sum.ByTag[t])
+		}
+	}
+	return nil
+}
+
+// printStats writes a table section, ordered by decreasing count.
+func printStats(tw *tabwriter.Writer, title string, groups map[string]*harhar.Stats) {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if groups[keys[i]].Count != groups[keys[j]].Count {
+			return groups[keys[i]].Count > groups[keys[j]].Count
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Fprintf(tw, "%s\tCOUNT\tKB\tP50\tP95\tP99\t\n", title)
+	for _, k := range keys {
+		s := groups[k]
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%dms\t%dms\t%dms\t\n", k, s.Count, float64(s.Bytes)/1024.0, s.P50, s.P95, s.P99)
+	}
+	fmt.Fprintln(tw, "\t\t\t\t\t\t")
+}
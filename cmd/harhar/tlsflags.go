@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// tlsFlags configure the TLS connections made by the harhar command.
+type tlsFlags struct {
+	insecure               bool
+	caCert                 string
+	cert, key              string
+	minVersion, maxVersion string
+}
+
+func (tf *tlsFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&tf.insecure, "insecure", false, "don't verify server certificates")
+	fs.StringVar(&tf.caCert, "cacert", "", "verify server certificates with the CA certificates in PEM `file`")
+	fs.StringVar(&tf.cert, "cert", "", "present the client certificate in PEM `file` (with --key)")
+	fs.StringVar(&tf.key, "key", "", "private key for --cert, in PEM `file`")
+	fs.StringVar(&tf.minVersion, "tls-min", "", "minimum TLS `version`: 1.0, 1.1, 1.2 or 1.3")
+	fs.StringVar(&tf.maxVersion, "tls-max", "", "maximum TLS `version`: 1.0, 1.1, 1.2 or 1.3")
+}
+
+// config returns the TLS configuration for the flags, or nil if none were
+// given.
+func (tf *tlsFlags) config() (*tls.Config, error) {
+	if !tf.insecure && tf.caCert == "" && tf.cert == "" && tf.key == "" && tf.minVersion == "" && tf.maxVersion == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{InsecureSkipVerify: tf.insecure}
+
+	if tf.caCert != "" {
+		pem, err := os.ReadFile(tf.caCert)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", tf.caCert)
+		}
+	}
+	if tf.cert != "" || tf.key != "" {
+		if tf.cert == "" || tf.key == "" {
+			return nil, errors.New("--cert and --key must be used together")
+		}
+		cert, err := tls.LoadX509KeyPair(tf.cert, tf.key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	var err error
+	if cfg.MinVersion, err = tlsVersion(tf.minVersion); err != nil {
+		return nil, err
+	}
+	if cfg.MaxVersion, err = tlsVersion(tf.maxVersion); err != nil {
+		return nil, err
+	}
+	if cfg.MinVersion != 0 && cfg.MaxVersion != 0 && cfg.MinVersion > cfg.MaxVersion {
+		return nil, errors.New("--tls-min is greater than --tls-max")
+	}
+	return cfg, nil
+}
+
+// tlsVersion parses a TLS version number such as "1.2", or "" for the
+// default.
+func tlsVersion(v string) (uint16, error) {
+	switch v {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q", v)
+}
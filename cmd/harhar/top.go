@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pbnjay/harhar"
+)
+
+// endpoint orderings for harhar top, in decreasing order of the value
+var topOrders = map[string]func(s *harhar.Stats) int64{
+	"total": func(s *harhar.Stats) int64 { return s.Time },
+	"count": func(s *harhar.Stats) int64 { return int64(s.Count) },
+	"max":   func(s *harhar.Stats) int64 { return int64(s.Max) },
+	"p50":   func(s *harhar.Stats) int64 { return int64(s.P50) },
+	"p95":   func(s *harhar.Stats) int64 { return int64(s.P95) },
+	"p99":   func(s *harhar.Stats) int64 { return int64(s.P99) },
+}
+
+func topCommand(args []string) error {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	n := fs.Int("n", 10, "show the top `n` entries and endpoints")
+	by := fs.String("sort", "total", "order endpoints by `key`: total, count, max, p50, p95 or p99")
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: harhar top <results.har> [-n 10] [--sort total|count|max|p50|p95|p99] [--tag t]")
+	}
+	order, ok := topOrders[*by]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q", *by)
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+
+	// entries keep their positions in the file
+	idx := make([]int, 0, len(h.Log.Entries))
+	for i := range h.Log.Entries {
+		if opts.Match(&h.Log.Entries[i]) {
+			idx = append(idx, i)
+		}
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return h.Log.Entries[idx[i]].Time > h.Log.Entries[idx[j]].Time
+	})
+	if len(idx) > *n {
+		idx = idx[:*n]
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "SLOWEST ENTRIES\tTIME\tSTATUS\tWAIT\t\n")
+	for _, i := range idx {
+		ent := &h.Log.Entries[i]
+		fmt.Fprintf(tw, "#%d %s %s\t%dms\t%d\t%dms\t\n", i, ent.Request.Method, ent.Request.URL,
+			ent.Time, ent.Response.StatusCode, ent.Timings.Wait)
+	}
+	fmt.Fprintln(tw, "\t\t\t\t")
+
+	sum := harhar.Summarize(harhar.Filter(h, opts))
+	keys := make([]string, 0, len(sum.ByEndpoint))
+	for k := range sum.ByEndpoint {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := order(sum.ByEndpoint[keys[i]]), order(sum.ByEndpoint[keys[j]])
+		if a != b {
+			return a > b
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > *n {
+		keys = keys[:*n]
+	}
+	fmt.Fprintf(tw, "ENDPOINT\tTOTAL\tCOUNT\tMAX\tP50\tP95\tP99\t\n")
+	for _, k := range keys {
+		s := sum.ByEndpoint[k]
+		fmt.Fprintf(tw, "%s\t%dms\t%d\t%dms\t%dms\t%dms\t%dms\t\n", k, s.Time, s.Count, s.Max, s.P50, s.P95, s.P99)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pbnjay/harhar"
+)
+
+func validateSpecCommand(args []string) error {
+	fs := flag.NewFlagSet("validate-spec", flag.ExitOnError)
+	specFile := fs.String("spec", "", "OpenAPI 3 document (`api.json`) to validate against, JSON encoded")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 || *specFile == "" {
+		return errors.New("usage: harhar validate-spec <results.har> --spec <api.json>")
+	}
+
+	h, err := harhar.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	spec, err := os.ReadFile(*specFile)
+	if err != nil {
+		return err
+	}
+	issues, err := harhar.ValidateOpenAPI(h, spec)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%d entries checked, %d issues", len(h.Log.Entries), len(issues))
+	}
+	fmt.Printf("%d entries checked, no issues\n", len(h.Log.Entries))
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/harhar"
+)
+
+// waterfall phases, with the ANSI colour and the character used without colour
+var phases = []struct {
+	name  string
+	color string
+	char  string
+}{
+	{"blocked", "\x1b[47m", "-"},
+	{"dns", "\x1b[46m", "d"},
+	{"connect", "\x1b[43m", "c"},
+	{"ssl", "\x1b[45m", "s"},
+	{"send", "\x1b[41m", ">"},
+	{"wait", "\x1b[42m", "w"},
+	{"receive", "\x1b[44m", "r"},
+}
+
+func waterfallCommand(args []string) error {
+	fs := flag.NewFlagSet("waterfall", flag.ExitOnError)
+	width := fs.Int("width", 60, "width of the chart in `columns`")
+	noColor := fs.Bool("no-color", false, "use letters instead of coloured bars")
+	var opts harhar.FilterOptions
+	tagFlag(fs, &opts)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 || *width < 1 {
+		return errors.New("usage: harhar waterfall <results.har> [--width 60] [--no-color] [--tag t]")
+	}
+
+	h, err := readSelected(files[0], opts)
+	if err != nil {
+		return err
+	}
+	h.SortEntries()
+	color := !*noColor && isTerminal(os.Stdout)
+
+	var first, last time.Time
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		start := e.StartTime()
+		end := start.Add(time.Duration(e.Time) * time.Millisecond)
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+	total := float64(last.Sub(first).Milliseconds())
+	if total <= 0 {
+		total = 1
+	}
+	scale := float64(*width) / total
+
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		t := e.Timings
+		ms := []int{t.Blocked, t.DNS, t.Connect, t.SSL, t.Send, t.Wait, t.Receive}
+
+		var bar strings.Builder
+		segment := func(pi, cells int) {
+			if color {
+				bar.WriteString(phases[pi].color + strings.Repeat(" ", cells) + "\x1b[0m")
+			} else {
+				bar.WriteString(strings.Repeat(phases[pi].char, cells))
+			}
+		}
+
+		pos := float64(e.StartTime().Sub(first).Milliseconds()) * scale
+		col := int(pos)
+		startCol, longest := col, 0
+		bar.WriteString(strings.Repeat(" ", col))
+		for pi, n := range ms {
+			if n > ms[longest] {
+				longest = pi
+			}
+			pos += float64(n) * scale
+			if cells := int(pos) - col; n > 0 && cells > 0 {
+				segment(pi, cells)
+				col += cells
+			}
+		}
+		if col == startCol && col < *width {
+			// always show something for very short entries
+			segment(longest, 1)
+			col++
+		}
+		if col < *width {
+			bar.WriteString(strings.Repeat(" ", *width-col))
+		}
+
+		label := e.Request.Method + " " + e.Request.URL
+		if len(label) > 50 {
+			label = label[:47] + "..."
+		}
+		fmt.Printf("%-50s %3d |%s| %dms\n", label, e.Response.StatusCode, bar.String(), e.Time)
+	}
+
+	var legend []string
+	for _, p := range phases {
+		if color {
+			legend = append(legend, p.color+"  \x1b[0m "+p.name)
+		} else {
+			legend = append(legend, p.char+" "+p.name)
+		}
+	}
+	fmt.Printf("\n%d requests over %dms: %s\n", len(h.Log.Entries), last.Sub(first).Milliseconds(), strings.Join(legend, "  "))
+	return nil
+}
+
+// isTerminal reports whether f is a character device, e.g. a terminal.
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
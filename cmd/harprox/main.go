@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"io"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
-	"net/url"
+	"net/http/httputil"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/pbnjay/harhar"
@@ -20,84 +24,143 @@ func main() {
 	rate := flag.Int("n", 5, "save HAR every `N` seconds")
 	addr := flag.String("i", ":6060", "`addr:post` to listen for requests")
 	prefix := flag.String("p", "", "`http://hostname/path` prefix to prepend on request paths")
+	routesFile := flag.String("routes", "", "proxy to several upstreams as configured in `routes.json` instead of -p")
 	outname := flag.String("o", "results.har", "output `filename.har` to save proxied requests")
 	serverRecorder := flag.Bool("s", false, "use server-side recorder for passthrough requests (less detail)")
+	splitUA := flag.Bool("split-ua", false, "save a separate HAR for each browser (by User-Agent)")
+	forward := flag.Bool("forward", false, "run as a forward HTTP(S) proxy for browsers instead of proxying to -p, decrypting HTTPS with the -ca certificate")
+	replayFile := flag.String("replay", "", "serve recorded responses from `capture.har` instead of contacting upstreams")
+	fallthru := flag.Bool("fallthrough", false, "with -replay, proxy requests which were not recorded to the upstream")
+	uiAddr := flag.String("ui", "", "serve a web UI for browsing the recorded requests at `addr:port`")
+	cache := flag.Bool("cache", false, "answer requests from an HTTP cache in memory when the upstream allows it")
+	caCert := flag.String("ca", "harprox-ca.pem", "CA certificate `ca.pem` for -forward, generated if missing")
+	caKey := flag.String("ca-key", "harprox-ca.key", "CA private key `ca.key` for -forward, generated if missing")
+	skipAssets := flag.Bool("skip-assets", false, "don't save bodies of images, fonts, video and other binary assets")
 	flag.Parse()
 
 	var hits uint32
 
-	var overHeaders http.Header
+	overHeaders := make(http.Header)
 	if *headerFile != "" {
-		raw, err := os.ReadFile(*headerFile)
-		if err != nil {
+		var err error
+		if overHeaders, err = readHeaderFile(*headerFile); err != nil {
 			log.Fatal(err)
 		}
-		for _, line := range strings.Split(string(raw), "\n") {
-			parts := strings.SplitN(line, ": ", 2)
-			overHeaders.Set(parts[0], parts[1])
-		}
 	}
 	rec := harhar.NewRecorder()
-	hcli := http.DefaultClient
-
-	pp, _ := url.Parse(*prefix)
-	realHost := pp.Host
+	if *skipAssets {
+		rec.SkipBodyTypes = harhar.DefaultSkipBodyTypes
+	}
+	// upstream failures are recorded too, with the error in the entry
+	rec.RecordErrors = true
+	// record response bodies as they stream through to the client
+	rec.LazyBodies = true
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		newurl := *prefix + r.URL.String()
-		passthrough, err := http.NewRequest(r.Method, newurl, r.Body)
-		if err != nil {
+	var routes []*route
+	var err error
+	switch {
+	case *routesFile != "" && (*prefix != "" || *forward):
+		log.Fatal("-routes cannot be combined with -p or -forward")
+	case *routesFile != "":
+		if routes, err = loadRoutes(*routesFile); err != nil {
 			log.Fatal(err)
 		}
-
-		for h, vals := range r.Header {
-			if strings.ToLower(h) == "host" {
-				passthrough.Header.Add("Host", realHost)
-				continue
-			}
-			for _, val := range vals {
-				passthrough.Header.Add(h, val)
-			}
-		}
-		for h := range overHeaders {
-			passthrough.Header.Set(h, overHeaders.Get(h))
-		}
-		resp, err := hcli.Do(passthrough)
-		if err != nil {
+		routeCapture(rec, routes)
+	case *prefix != "":
+		// a single route for everything
+		rt := &route{Upstream: *prefix}
+		if rt.target, err = parseUpstream(*prefix); err != nil {
 			log.Fatal(err)
 		}
+		routes = []*route{rt}
+	case !*forward:
+		log.Fatal("one of -p, -routes or -forward is required")
+	}
 
-		for h, vals := range resp.Header {
-			for _, val := range vals {
-				w.Header().Add(h, val)
+	proxy := &httputil.ReverseProxy{
+		// hop-by-hop headers are already removed from the outgoing request
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// without a route, forwarded requests already carry their
+			// destination and should reach it as though the browser had
+			// sent them
+			rt := routeFrom(pr.In.Context())
+			if rt != nil {
+				rt.rewritePath(pr.Out.URL)
+				pr.SetURL(rt.target)
+				pr.SetXForwarded()
 			}
-		}
-		w.WriteHeader(resp.StatusCode)
-		io.Copy(w, resp.Body)
+			if pr.In.Header.Get("Upgrade") != "" {
+				// without compression extensions WebSocket messages are
+				// recorded readably, see Entry.WebSocketMessages
+				pr.Out.Header.Del("Sec-WebSocket-Extensions")
+			}
+			for h := range overHeaders {
+				pr.Out.Header.Set(h, overHeaders.Get(h))
+			}
+			if rt != nil {
+				for name, val := range rt.Headers {
+					pr.Out.Header.Set(name, val)
+				}
+			}
+		},
+		// send each write on immediately, e.g. for server-sent events
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("unable to proxy %s %s: %v", r.Method, r.URL, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		proxy.ServeHTTP(w, r)
 		atomic.AddUint32(&hits, 1)
 	})
 
+	// stop on Ctrl-C or SIGTERM, a second signal exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	saving := make(chan struct{})
 	go func() {
+		defer close(saving)
+		ticker := time.NewTicker(time.Second * time.Duration(*rate))
+		defer ticker.Stop()
 		var lasthits uint32
-		for range time.NewTicker(time.Second * time.Duration(*rate)).C {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
 			newhits := atomic.LoadUint32(&hits)
 			if newhits == lasthits {
 				continue
 			}
 			lasthits = newhits
-
-			size, err := rec.WriteFile(*outname)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			// it's always good to report size when logging since memory usage
-			// will grow pretty quickly if you're not careful.
-			log.Printf("[%d hits] -- wrote %s (%.1fkb)\n", newhits, *outname, float64(size)/1024.0)
+			saveHAR(rec, *outname, *splitUA, newhits)
 		}
 	}()
 
-	srv := http.Server{Addr: *addr, Handler: nil}
+	// reaches the upstreams, or stands in for them
+	transport := rec.RoundTripper
+	if *replayFile != "" {
+		capture, err := harhar.ReadFile(*replayFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		replayer := harhar.NewReplayer(capture)
+		if *fallthru {
+			replayer.Fallback = transport
+		}
+		transport = replayer
+	}
+	if *cache {
+		// fresh responses never reach the upstream (or -replay), and each
+		// entry records the cache state before and after the request
+		transport = harhar.NewCachingTransport(transport)
+	}
+
+	srv := http.Server{Addr: *addr, Handler: http.DefaultServeMux}
 
 	ln, err := net.Listen("tcp4", *addr)
 	if err != nil {
@@ -105,20 +168,137 @@ func main() {
 	}
 	baseURL := "http://" + ln.Addr().String()
 	log.Println("Listening at " + baseURL + "/...")
-	log.Printf("  Requests to %s/<endpoint> will proxy to %s/<endpoint>", baseURL, *prefix)
+	if *forward {
+		log.Printf("  Configure %s as the HTTP and HTTPS proxy, and trust %s to record HTTPS", baseURL, *caCert)
+	}
+	for _, rt := range routes {
+		from, to := rt.describe()
+		log.Printf("  Requests to %s%s will proxy to %s", baseURL, from, to)
+	}
+	if *replayFile != "" {
+		if *fallthru {
+			log.Printf("  Responses recorded in %s are served without contacting the upstream, other requests are proxied", *replayFile)
+		} else {
+			log.Printf("  Only responses recorded in %s are served, the upstream is never contacted", *replayFile)
+		}
+	}
 	log.Println("")
 
 	if *serverRecorder {
 		// server-side har logging (FYI less network detail)
 		srv.Handler = rec
+		proxy.Transport = transport
 	} else {
 		// since we're proxying every request,
 		// client side works great and gets more detail
-		hcli = &http.Client{Transport: rec}
+		rec.RoundTripper = transport
+		proxy.Transport = rec
+	}
+	if routes != nil {
+		srv.Handler = withRoutes(routes, srv.Handler)
+	}
+	if *forward {
+		ca, err := loadCA(*caCert, *caKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv.Handler = &mitmProxy{next: srv.Handler, ca: ca}
+	}
+
+	// the UI gets its own listener, so that it is never proxied
+	var uiSrv *http.Server
+	if *uiAddr != "" {
+		uiLn, err := net.Listen("tcp4", *uiAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		uiSrv = &http.Server{Handler: uiHandler(rec)}
+		log.Printf("Web UI at http://%s/ (no authentication, keep it private)", uiLn.Addr())
+		go func() {
+			if err := uiSrv.Serve(uiLn); err != http.ErrServerClosed {
+				log.Println("web UI stopped", err)
+			}
+		}()
 	}
 
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		stop()
+		if uiSrv != nil {
+			uiSrv.Close()
+		}
+		log.Println("shutting down, waiting for requests in progress (Ctrl-C again to quit now)...")
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		// hijacked connections (WebSockets and CONNECT tunnels) are not
+		// waited for, so their entries are only saved if already closed
+		if err := srv.Shutdown(sctx); err != nil {
+			log.Println("unable to finish all requests", err)
+		}
+	}()
+
 	err = srv.Serve(ln)
-	if err != nil {
+	if err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
+	<-drained
+	<-saving
+	if n := atomic.LoadUint32(&hits); n > 0 {
+		saveHAR(rec, *outname, *splitUA, n)
+	}
+}
+
+// how long shutdown waits for requests in progress before saving anyway
+const shutdownTimeout = 30 * time.Second
+
+// saveHAR writes the requests recorded so far to outname, or to one file per
+// browser if splitUA is set.
+func saveHAR(rec *harhar.Recorder, outname string, splitUA bool, hits uint32) {
+	h := rec.Snapshot()
+	setBrowser(h)
+	parts := map[string]*harhar.HAR{"": h}
+	if splitUA {
+		parts = splitByBrowser(h)
+	}
+	for key, part := range parts {
+		name := outname
+		if key != "" {
+			ext := filepath.Ext(name)
+			name = strings.TrimSuffix(name, ext) + "-" + key + ext
+		}
+		size, err := part.WriteFile(name)
+		if err != nil {
+			// keep recording, the next save may succeed
+			log.Println("unable to save HAR", err)
+			continue
+		}
+
+		// it's always good to report size when logging since memory usage
+		// will grow pretty quickly if you're not careful.
+		log.Printf("[%d hits] -- wrote %s (%.1fkb)\n", hits, name, float64(size)/1024.0)
+	}
+}
+
+// readHeaderFile reads "Name: value" lines from filename, skipping blank
+// lines and #-comments.
+func readHeaderFile(filename string) (http.Header, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	hdr := make(http.Header)
+	for i, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, val, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"Name: value\", got %q", filename, i+1, line)
+		}
+		hdr.Set(strings.TrimSpace(name), strings.TrimSpace(val))
+	}
+	return hdr, nil
 }
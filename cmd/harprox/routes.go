@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pbnjay/harhar"
+)
+
+// routeConfig is the format of the -routes file, e.g.
+//
+//	{"routes": [
+//	  {"name": "users", "host": "*.local", "pathPrefix": "/users/", "upstream": "http://localhost:8081"},
+//	  {"name": "static", "pathPrefix": "/static/", "stripPrefix": true, "upstream": "http://localhost:8082", "skipBodies": true},
+//	  {"name": "health", "pathPrefix": "/healthz", "upstream": "http://localhost:8081", "record": false},
+//	  {"upstream": "http://localhost:8080", "headers": {"X-Env": "dev"}}
+//	]}
+type routeConfig struct {
+	Routes []*route `json:"routes"`
+}
+
+// route proxies the requests it matches to an upstream server. Requests are
+// sent to the first route which matches.
+type route struct {
+	// Name of the route, recorded as a "route:<name>" tag on its entries.
+	// Defaults to the route's position in the file, starting from 1.
+	Name string `json:"name"`
+
+	// Host, if set, matches the request host (without the port), and may
+	// be a glob such as "*.example.com".
+	Host string `json:"host"`
+
+	// PathPrefix, if set, matches the start of the request path. If
+	// StripPrefix is set, it is removed before the request is proxied.
+	PathPrefix  string `json:"pathPrefix"`
+	StripPrefix bool   `json:"stripPrefix"`
+
+	// Upstream is the URL prefix to prepend on request paths.
+	Upstream string `json:"upstream"`
+
+	// Headers overwrite request headers on passthrough, after any loaded
+	// with -h.
+	Headers map[string]string `json:"headers"`
+
+	// Record, if false, passes requests through without recording them.
+	Record *bool `json:"record"`
+
+	// SkipBodies records entries without request and response bodies.
+	SkipBodies bool `json:"skipBodies"`
+
+	target *url.URL
+}
+
+// loadRoutes reads and checks a routes file. Only JSON is supported, YAML
+// files are rejected with a clear error rather than misparsed.
+func loadRoutes(filename string) ([]*route, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] != '{' {
+		return nil, errors.New(filename + ": routes must be a JSON object (YAML is not supported, convert it with e.g. yq -o=json)")
+	}
+	var cfg routeConfig
+	if err = json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(cfg.Routes) == 0 {
+		return nil, errors.New(filename + ": no routes defined")
+	}
+	for i, rt := range cfg.Routes {
+		if rt.Name == "" {
+			rt.Name = fmt.Sprint(i + 1)
+		}
+		if _, err = path.Match(rt.Host, ""); err != nil {
+			return nil, fmt.Errorf("%s: route %s: bad host pattern %q", filename, rt.Name, rt.Host)
+		}
+		if rt.target, err = parseUpstream(rt.Upstream); err != nil {
+			return nil, fmt.Errorf("%s: route %s: %w", filename, rt.Name, err)
+		}
+	}
+	return cfg.Routes, nil
+}
+
+// parseUpstream parses an upstream URL prefix.
+func parseUpstream(upstream string) (*url.URL, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("upstream %q must be an http:// or https:// URL", upstream)
+	}
+	return u, nil
+}
+
+func (rt *route) matches(r *http.Request) bool {
+	if rt.Host != "" {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if ok, _ := path.Match(strings.ToLower(rt.Host), strings.ToLower(host)); !ok {
+			return false
+		}
+	}
+	return strings.HasPrefix(r.URL.Path, rt.PathPrefix)
+}
+
+func (rt *route) record() bool {
+	return rt.Record == nil || *rt.Record
+}
+
+// rewritePath removes PathPrefix from u if StripPrefix is set.
+func (rt *route) rewritePath(u *url.URL) {
+	if !rt.StripPrefix || rt.PathPrefix == "" {
+		return
+	}
+	u.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(u.Path, rt.PathPrefix), "/")
+	u.RawPath = ""
+}
+
+type routeKey struct{}
+
+// routeFrom returns the route chosen for a request by withRoutes, or nil.
+func routeFrom(ctx context.Context) *route {
+	rt, _ := ctx.Value(routeKey{}).(*route)
+	return rt
+}
+
+// withRoutes chooses the route for each request before passing it to next,
+// so that the recorders and the proxy all see the same choice. Named routes
+// tag their entries.
+func withRoutes(routes []*route, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rt := range routes {
+			if !rt.matches(r) {
+				continue
+			}
+			ctx := context.WithValue(r.Context(), routeKey{}, rt)
+			if rt.Name != "" {
+				ctx = harhar.WithTags(ctx, "route:"+rt.Name)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		http.Error(w, "no route for "+r.Host+r.URL.Path, http.StatusNotFound)
+	})
+}
+
+// routeCapture applies the capture settings of routes to rec.
+func routeCapture(rec *harhar.Recorder, routes []*route) {
+	byTag := make(map[string]*route)
+	for _, rt := range routes {
+		if rt.Name != "" {
+			byTag["route:"+rt.Name] = rt
+		}
+	}
+
+	rec.Sampler = func(r *http.Request) bool {
+		rt := routeFrom(r.Context())
+		return rt == nil || rt.record()
+	}
+	rec.Transform = func(ent *harhar.Entry) {
+		for _, tag := range ent.Tags {
+			if rt := byTag[tag]; rt != nil && rt.SkipBodies {
+				ent.StripBodies()
+			}
+		}
+	}
+}
+
+// describe summarizes the paths the route proxies from and to, for logging.
+func (rt *route) describe() (from, to string) {
+	prefix := "/" + strings.TrimPrefix(rt.PathPrefix, "/")
+	from = prefix + "<endpoint>"
+	if rt.Host != "" {
+		from += " on host " + rt.Host
+	}
+	to = strings.TrimSuffix(rt.Upstream, "/") + prefix + "<endpoint>"
+	if rt.StripPrefix {
+		to = strings.TrimSuffix(rt.Upstream, "/") + "/<endpoint>"
+	}
+	return from, to
+}
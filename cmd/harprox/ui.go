@@ -0,0 +1,27 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/pbnjay/harhar"
+)
+
+//go:embed ui.html
+var uiPage []byte
+
+// uiHandler serves a page listing the entries recorded by rec as they
+// arrive, with filtering, a detail view, and buttons to pause, clear and
+// download the HAR. It polls the recorder's AdminHandler, which is served
+// alongside.
+func uiHandler(rec *harhar.Recorder) http.Handler {
+	admin := rec.AdminHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			admin.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(uiPage)
+	})
+}
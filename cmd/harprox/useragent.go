@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pbnjay/harhar"
+)
+
+// product tokens checked in order, since most browsers also claim to be
+// several of the others (e.g. Edge includes Chrome and Safari tokens)
+var browserTokens = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`OPR/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+}
+
+// parseUserAgent returns the browser name and version described by ua. For
+// non-browser clients the first product token is used (e.g. "curl/8.0").
+func parseUserAgent(ua string) *harhar.Creator {
+	for _, bt := range browserTokens {
+		if m := bt.re.FindStringSubmatch(ua); m != nil {
+			return &harhar.Creator{Name: bt.name, Version: m[1], Comment: ua}
+		}
+	}
+	product := strings.Fields(ua)
+	if len(product) == 0 {
+		return &harhar.Creator{Name: "unknown", Version: "0"}
+	}
+	name, version, _ := strings.Cut(product[0], "/")
+	return &harhar.Creator{Name: name, Version: version, Comment: ua}
+}
+
+func userAgent(e *harhar.Entry) string {
+	for _, h := range e.Request.Headers {
+		if http.CanonicalHeaderKey(h.Name) == "User-Agent" {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// setBrowser fills in the browser block from the most common User-Agent.
+func setBrowser(h *harhar.HAR) {
+	counts := make(map[string]int)
+	best := ""
+	for i := range h.Log.Entries {
+		ua := userAgent(&h.Log.Entries[i])
+		if ua == "" {
+			continue
+		}
+		counts[ua]++
+		if counts[ua] > counts[best] {
+			best = ua
+		}
+	}
+	if best != "" {
+		h.Log.Browser = parseUserAgent(best)
+	}
+}
+
+// splitByBrowser divides the archive into one archive per browser, keyed by
+// a filename-safe browser name and major version.
+func splitByBrowser(h *harhar.HAR) map[string]*harhar.HAR {
+	res := make(map[string]*harhar.HAR)
+	for _, ent := range h.Log.Entries {
+		b := parseUserAgent(userAgent(&ent))
+		major, _, _ := strings.Cut(b.Version, ".")
+		key := strings.ToLower(strings.ReplaceAll(b.Name, " ", "-"))
+		if major != "" {
+			key += "-" + major
+		}
+		key = filepath.Base(key)
+
+		part, ok := res[key]
+		if !ok {
+			part = &harhar.HAR{Log: h.Log}
+			part.Log.Entries = nil
+			part.Log.Browser = b
+			res[key] = part
+		}
+		part.Log.Entries = append(part.Log.Entries, ent)
+	}
+	return res
+}
@@ -0,0 +1,88 @@
+package harhar
+
+import (
+	"sort"
+	"strings"
+)
+
+// StripBodies removes all request and response body content from the
+// archive. MIME types and sizes are kept.
+func (h *HAR) StripBodies() {
+	for i := range h.Log.Entries {
+		h.Log.Entries[i].StripBodies()
+	}
+}
+
+// StripBodies removes the request and response body content of the entry,
+// including GraphQL variables parsed from the request body and the data of
+// WebSocket messages. MIME types and sizes are kept.
+func (ent *Entry) StripBodies() {
+	ent.Request.Body = BodyType{MIMEType: ent.Request.Body.MIMEType}
+	if ent.Request.GraphQL != nil {
+		ent.Request.GraphQL.Variables = nil
+	}
+	ent.Response.Body.Content = ""
+	ent.Response.Body.Encoding = ""
+	ent.Response.Body.FileRef = ""
+	ent.Response.Body.compressed = nil
+	for i := range ent.WebSocketMessages {
+		ent.WebSocketMessages[i].Data = ""
+	}
+}
+
+// Dedupe removes entries which repeat an earlier entry's method, URL, request
+// body and response body, and returns the number of entries removed.
+func (h *HAR) Dedupe() int {
+	seen := make(map[string]bool, len(h.Log.Entries))
+	kept := h.Log.Entries[:0]
+	for _, ent := range h.Log.Entries {
+		key := entryHash(&ent)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, ent)
+	}
+	n := len(h.Log.Entries) - len(kept)
+	h.Log.Entries = kept
+	return n
+}
+
+// SortEntries orders entries by start time. Entries with equal start times
+// keep their relative order.
+func (h *HAR) SortEntries() {
+	sort.SliceStable(h.Log.Entries, func(i, j int) bool {
+		return h.Log.Entries[i].StartTime().Before(h.Log.Entries[j].StartTime())
+	})
+}
+
+// SortFields orders the headers, query parameters and cookies of every entry
+// by name, so archives of identical traffic compare equal. Repeated names
+// keep their relative order, which can be significant.
+func (h *HAR) SortFields() {
+	for i := range h.Log.Entries {
+		sortEntryFields(&h.Log.Entries[i])
+	}
+}
+
+func sortEntryFields(ent *Entry) {
+	sortPairs(ent.Request.Headers)
+	sortPairs(ent.Request.QueryParams)
+	sortPairs(ent.Response.Headers)
+	sortPairs(ent.Request.Trailers)
+	sortPairs(ent.Response.Trailers)
+	sortCookies(ent.Request.Cookies)
+	sortCookies(ent.Response.Cookies)
+}
+
+func sortPairs(pairs []NameValuePair) {
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return strings.ToLower(pairs[i].Name) < strings.ToLower(pairs[j].Name)
+	})
+}
+
+func sortCookies(cookies []Cookie) {
+	sort.SliceStable(cookies, func(i, j int) bool {
+		return cookies[i].Name < cookies[j].Name
+	})
+}
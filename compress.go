@@ -0,0 +1,81 @@
+package harhar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+)
+
+// bodies smaller than this are not worth compressing in memory
+const minCompressSize = 1024
+
+// compressBodies gzips the entry's body contents in memory. They are
+// decompressed transparently when the entry is encoded to JSON, or by Text.
+func compressBodies(ent *Entry) {
+	if len(ent.Request.Body.Content) >= minCompressSize {
+		if z := gzipString(ent.Request.Body.Content); z != nil {
+			ent.Request.Body.compressed = z
+			ent.Request.Body.Content = ""
+		}
+	}
+	if len(ent.Response.Body.Content) >= minCompressSize {
+		if z := gzipString(ent.Response.Body.Content); z != nil {
+			ent.Response.Body.compressed = z
+			ent.Response.Body.Content = ""
+		}
+	}
+}
+
+func gzipString(s string) []byte {
+	buf := &bytes.Buffer{}
+	zw, _ := gzip.NewWriterLevel(buf, gzip.BestSpeed)
+	if _, err := io.WriteString(zw, s); err != nil {
+		return nil
+	}
+	if err := zw.Close(); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func gunzipString(z []byte) string {
+	zr, err := gzip.NewReader(bytes.NewReader(z))
+	if err != nil {
+		return ""
+	}
+	data, _ := io.ReadAll(zr)
+	return string(data)
+}
+
+// Text returns the content of the body, decompressing it if it was
+// compressed in memory (see Recorder.CompressBodies).
+func (b *BodyType) Text() string {
+	if b.compressed != nil {
+		return gunzipString(b.compressed)
+	}
+	return b.Content
+}
+
+// Text returns the content of the body, decompressing it if it was
+// compressed in memory (see Recorder.CompressBodies).
+func (b *BodyResponseType) Text() string {
+	if b.compressed != nil {
+		return gunzipString(b.compressed)
+	}
+	return b.Content
+}
+
+// MarshalJSON implements json.Marshaler, decompressing in-memory content.
+func (b BodyType) MarshalJSON() ([]byte, error) {
+	type plain BodyType
+	b.Content = b.Text()
+	return json.Marshal(plain(b))
+}
+
+// MarshalJSON implements json.Marshaler, decompressing in-memory content.
+func (b BodyResponseType) MarshalJSON() ([]byte, error) {
+	type plain BodyResponseType
+	b.Content = b.Text()
+	return json.Marshal(plain(b))
+}
@@ -0,0 +1,158 @@
+package harhar
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var errExternalBody = errors.New("harhar: body is stored externally, see HAR.LoadBodies")
+
+// ToHTTPRequest rebuilds a live request from the recorded method, URL,
+// headers, cookies and body. Bodies stored externally must be loaded first
+// (see HAR.LoadBodies), and file uploads recorded without their contents are
+// sent empty.
+func (r *Request) ToHTTPRequest(ctx context.Context) (*http.Request, error) {
+	if r.Body.FileRef != "" && r.Body.Content == "" && r.Body.compressed == nil {
+		return nil, errExternalBody
+	}
+	body, contentType, err := r.Body.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyReader io.Reader
+	if len(body) > 0 {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range r.Headers {
+		switch {
+		case strings.HasPrefix(h.Name, ":"):
+			// HTTP/2 pseudo-headers are derived from the request
+		case strings.EqualFold(h.Name, "Host"):
+			req.Host = h.Value
+		case strings.EqualFold(h.Name, "Content-Length"):
+			// the body may have been re-encoded
+		default:
+			req.Header.Add(h.Name, h.Value)
+		}
+	}
+	if req.Header.Get("Cookie") == "" {
+		for _, c := range r.Cookies {
+			req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	if len(body) > 0 && contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// bytes returns the raw body and its content type, decoding base64 content
+// or encoding Params as a form.
+func (b *BodyType) bytes() ([]byte, string, error) {
+	if text := b.Text(); text != "" || len(b.Params) == 0 {
+		if b.Encoding == "base64" {
+			data, err := base64.StdEncoding.DecodeString(text)
+			return data, b.MIMEType, err
+		}
+		return []byte(text), b.MIMEType, nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(b.MIMEType)
+	if mediaType != "multipart/form-data" {
+		form := url.Values{}
+		for _, p := range b.Params {
+			form.Add(p.Name, p.Value)
+		}
+		return []byte(form.Encode()), b.MIMEType, nil
+	}
+
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	if params["boundary"] != "" {
+		if err := mw.SetBoundary(params["boundary"]); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, p := range b.Params {
+		h := make(textproto.MIMEHeader)
+		disposition := map[string]string{"name": p.Name}
+		if p.FileName != "" {
+			disposition["filename"] = p.FileName
+			ct := p.ContentType
+			if ct == "" {
+				ct = "application/octet-stream"
+			}
+			h.Set("Content-Type", ct)
+		}
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data", disposition))
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		io.WriteString(w, p.Value)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+// ToHTTPResponse rebuilds a response from the recorded status, headers,
+// trailers and body, e.g. to serve it from a mock server. The body is the
+// decoded content, so Content-Encoding is removed and Content-Length set to
+// match it. Bodies stored externally must be loaded first.
+func (r *Response) ToHTTPResponse() (*http.Response, error) {
+	if r.Body.FileRef != "" && r.Body.Content == "" && r.Body.compressed == nil {
+		return nil, errExternalBody
+	}
+	body := []byte(r.Body.Text())
+	if r.Body.Encoding == "base64" {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &http.Response{
+		Status:        strconv.Itoa(r.StatusCode) + " " + r.StatusText,
+		StatusCode:    r.StatusCode,
+		Proto:         r.HTTPVersion,
+		Header:        make(http.Header, len(r.Headers)),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+	resp.ProtoMajor, resp.ProtoMinor, _ = http.ParseHTTPVersion(r.HTTPVersion)
+	for _, h := range r.Headers {
+		resp.Header.Add(h.Name, h.Value)
+	}
+	for _, h := range r.Trailers {
+		if resp.Trailer == nil {
+			resp.Trailer = make(http.Header, len(r.Trailers))
+		}
+		resp.Trailer.Add(h.Name, h.Value)
+	}
+	// recorded bodies are already decoded
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	if strings.TrimSpace(r.StatusText) == "" {
+		resp.Status = strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode)
+	}
+	return resp, nil
+}
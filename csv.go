@@ -0,0 +1,40 @@
+package harhar
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// csvColumns are the columns written by WriteCSV.
+var csvColumns = []string{
+	"start", "method", "url", "status", "mime_type",
+	"request_headers_size", "request_body_size", "response_headers_size", "response_body_size",
+	"time", "blocked", "dns", "connect", "ssl", "send", "wait", "receive",
+}
+
+// WriteCSV writes one row per entry to w, with the start time, method, URL,
+// status, response MIME type, sizes and each timing phase (in milliseconds),
+// for spreadsheet analysis. The first row names the columns.
+func (h *HAR) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		t := e.Timings
+		row := []string{e.Start, e.Request.Method, e.Request.URL, strconv.Itoa(e.Response.StatusCode), e.Response.Body.MIMEType}
+		for _, n := range []int{
+			e.Request.HeadersSize, e.Request.BodySize, e.Response.HeadersSize, e.Response.BodySize,
+			e.Time, t.Blocked, t.DNS, t.Connect, t.SSL, t.Send, t.Wait, t.Receive,
+		} {
+			row = append(row, strconv.Itoa(n))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,86 @@
+package harhar
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToCurl returns a curl command line which repeats the entry's request,
+// including its method, headers and body. Binary bodies are piped in through
+// base64 so the command stays copy-pasteable.
+func (e *Entry) ToCurl() (string, error) {
+	r := &e.Request
+	if r.Body.FileRef != "" && r.Body.Content == "" && r.Body.compressed == nil {
+		return "", errExternalBody
+	}
+	body, contentType, err := r.Body.bytes()
+	if err != nil {
+		return "", err
+	}
+
+	var args []string
+	switch {
+	case r.Method == "HEAD":
+		// with -X HEAD, curl would wait for a body which never arrives
+		args = append(args, "--head")
+	case (len(body) > 0 && r.Method != "POST") || (len(body) == 0 && r.Method != "GET"):
+		args = append(args, "-X", r.Method)
+	}
+	if strings.HasPrefix(e.Response.HTTPVersion, "HTTP/2") {
+		args = append(args, "--http2")
+	}
+
+	host := ""
+	if u, err := url.Parse(r.URL); err == nil {
+		host = u.Host
+	}
+	compressed := false
+	for _, h := range r.Headers {
+		switch {
+		case strings.HasPrefix(h.Name, ":"),
+			strings.EqualFold(h.Name, "Content-Length"),
+			strings.EqualFold(h.Name, "Host") && h.Value == host:
+			// set by curl
+		case strings.EqualFold(h.Name, "Accept-Encoding"):
+			// let curl negotiate and decode
+			if !compressed {
+				args = append(args, "--compressed")
+				compressed = true
+			}
+		case strings.EqualFold(h.Name, "Content-Type") && contentType != "":
+			// may have changed if the body was re-encoded
+		default:
+			args = append(args, "-H", h.Name+": "+h.Value)
+		}
+	}
+
+	prefix := ""
+	if len(body) > 0 {
+		if contentType != "" {
+			args = append(args, "-H", "Content-Type: "+contentType)
+		}
+		if utf8.Valid(body) {
+			args = append(args, "--data-binary", string(body))
+		} else {
+			prefix = "printf '%s' " + shellQuote(base64.StdEncoding.EncodeToString(body)) + " | base64 -d | "
+			args = append(args, "--data-binary", "@-")
+		}
+	}
+	args = append(args, r.URL)
+
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return prefix + "curl " + strings.Join(quoted, " "), nil
+}
+
+// shellQuote quotes s for a POSIX shell, if needed.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@=,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,104 @@
+package harhar
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://example.com/a?b=c", "'https://example.com/a?b=c'"},
+		{"https://example.com/a-b_c.d", "https://example.com/a-b_c.d"},
+		{"--compressed", "--compressed"},
+		{"", "''"},
+		{"Accept: */*", "'Accept: */*'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a\nb", "'a\nb'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToCurl(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		headers []NameValuePair
+		body    BodyType
+		version string
+		want    string
+	}{
+		{
+			name:   "get",
+			method: "GET",
+			want:   "curl https://example.com/x",
+		},
+		{
+			name:   "head",
+			method: "HEAD",
+			want:   "curl --head https://example.com/x",
+		},
+		{
+			name:   "delete",
+			method: "DELETE",
+			want:   "curl -X DELETE https://example.com/x",
+		},
+		{
+			name:   "post",
+			method: "POST",
+			body:   BodyType{MIMEType: "application/json", Content: `{"a":"it's"}`},
+			want:   `curl -H 'Content-Type: application/json' --data-binary '{"a":"it'\''s"}' https://example.com/x`,
+		},
+		{
+			name:   "put",
+			method: "PUT",
+			body:   BodyType{MIMEType: "text/plain", Content: "hi"},
+			want:   "curl -X PUT -H 'Content-Type: text/plain' --data-binary hi https://example.com/x",
+		},
+		{
+			name:   "binary body",
+			method: "POST",
+			body:   BodyType{MIMEType: "application/octet-stream", Content: "AP8=", Encoding: "base64"},
+			want:   "printf '%s' AP8= | base64 -d | curl -H 'Content-Type: application/octet-stream' --data-binary @- https://example.com/x",
+		},
+		{
+			name:   "headers",
+			method: "GET",
+			headers: []NameValuePair{
+				{Name: "Host", Value: "example.com"},
+				{Name: "Accept-Encoding", Value: "gzip"},
+				{Name: "accept-encoding", Value: "br"},
+				{Name: "X-Quote", Value: "a'b"},
+				{Name: "Content-Length", Value: "0"},
+			},
+			want: `curl --compressed -H 'X-Quote: a'\''b' https://example.com/x`,
+		},
+		{
+			name:    "http2",
+			method:  "GET",
+			headers: []NameValuePair{{Name: ":authority", Value: "example.com"}},
+			version: "HTTP/2.0",
+			want:    "curl --http2 https://example.com/x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ent Entry
+			ent.Request.Method = tt.method
+			ent.Request.URL = "https://example.com/x"
+			ent.Request.Headers = tt.headers
+			ent.Request.Body = tt.body
+			ent.Response.HTTPVersion = tt.version
+			got, err := ent.ToCurl()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
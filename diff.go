@@ -0,0 +1,152 @@
+package harhar
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DiffKind describes how an entry differs between two archives.
+type DiffKind string
+
+const (
+	DiffAdded     DiffKind = "added"
+	DiffRemoved   DiffKind = "removed"
+	DiffChanged   DiffKind = "changed"
+	DiffUnchanged DiffKind = "unchanged"
+)
+
+// EntryDiff describes the differences between the entries for one request
+// in two archives.
+type EntryDiff struct {
+	Kind   DiffKind `json:"kind"`
+	Method string   `json:"method"`
+	URL    string   `json:"url"`
+
+	// OldStatus and NewStatus are 0 for added and removed entries.
+	OldStatus int `json:"oldStatus,omitempty"`
+	NewStatus int `json:"newStatus,omitempty"`
+
+	// Headers lists response headers which were added, removed or changed.
+	Headers []HeaderDiff `json:"headers,omitempty"`
+
+	// BodyChanged is true if the response bodies differ.
+	BodyChanged bool `json:"bodyChanged,omitempty"`
+
+	// TimeDelta is the new entry's total time minus the old entry's, in
+	// milliseconds.
+	TimeDelta int `json:"timeDelta"`
+}
+
+// HeaderDiff describes a response header which differs. Old or New is empty
+// if the header was added or removed.
+type HeaderDiff struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// DefaultDiffIgnoreHeaders lists response headers which usually differ
+// between captures of identical traffic.
+var DefaultDiffIgnoreHeaders = []string{"Date", "Expires", "Last-Modified", "Age", "Set-Cookie"}
+
+// Diff compares two archives, matching entries by method and URL. Repeated
+// requests are matched in order. Response headers listed in ignoreHeaders
+// are not compared. Results are ordered as the entries of after, followed by
+// entries removed from before.
+func Diff(before, after *HAR, ignoreHeaders ...string) []EntryDiff {
+	ignore := make(map[string]bool, len(ignoreHeaders))
+	for _, name := range ignoreHeaders {
+		ignore[http.CanonicalHeaderKey(name)] = true
+	}
+
+	type key struct{ method, url string }
+	pending := make(map[key][]int)
+	for i, e := range before.Log.Entries {
+		k := key{e.Request.Method, e.Request.URL}
+		pending[k] = append(pending[k], i)
+	}
+
+	matched := make([]bool, len(before.Log.Entries))
+	var res []EntryDiff
+	for i := range after.Log.Entries {
+		ne := &after.Log.Entries[i]
+		k := key{ne.Request.Method, ne.Request.URL}
+		if len(pending[k]) == 0 {
+			res = append(res, EntryDiff{
+				Kind: DiffAdded, Method: ne.Request.Method, URL: ne.Request.URL,
+				NewStatus: ne.Response.StatusCode, TimeDelta: ne.Time,
+			})
+			continue
+		}
+		oi := pending[k][0]
+		pending[k] = pending[k][1:]
+		matched[oi] = true
+		res = append(res, diffEntries(&before.Log.Entries[oi], ne, ignore))
+	}
+	for i, m := range matched {
+		if m {
+			continue
+		}
+		oe := &before.Log.Entries[i]
+		res = append(res, EntryDiff{
+			Kind: DiffRemoved, Method: oe.Request.Method, URL: oe.Request.URL,
+			OldStatus: oe.Response.StatusCode, TimeDelta: -oe.Time,
+		})
+	}
+	return res
+}
+
+func diffEntries(oe, ne *Entry, ignore map[string]bool) EntryDiff {
+	d := EntryDiff{
+		Kind:      DiffUnchanged,
+		Method:    ne.Request.Method,
+		URL:       ne.Request.URL,
+		OldStatus: oe.Response.StatusCode,
+		NewStatus: ne.Response.StatusCode,
+		TimeDelta: ne.Time - oe.Time,
+	}
+	d.Headers = diffHeaders(oe.Response.Headers, ne.Response.Headers, ignore)
+	d.BodyChanged = oe.Response.Body.Text() != ne.Response.Body.Text() ||
+		oe.Response.Body.Encoding != ne.Response.Body.Encoding
+	if d.OldStatus != d.NewStatus || len(d.Headers) > 0 || d.BodyChanged {
+		d.Kind = DiffChanged
+	}
+	return d
+}
+
+// diffHeaders compares headers by name, joining repeated values.
+func diffHeaders(old, new []NameValuePair, ignore map[string]bool) []HeaderDiff {
+	collect := func(pairs []NameValuePair) map[string]string {
+		res := make(map[string]string, len(pairs))
+		for _, p := range pairs {
+			name := http.CanonicalHeaderKey(p.Name)
+			if ignore[name] {
+				continue
+			}
+			if v, ok := res[name]; ok {
+				res[name] = v + ", " + p.Value
+			} else {
+				res[name] = p.Value
+			}
+		}
+		return res
+	}
+	oh, nh := collect(old), collect(new)
+
+	var res []HeaderDiff
+	for name, ov := range oh {
+		if nv, ok := nh[name]; !ok || nv != ov {
+			res = append(res, HeaderDiff{Name: name, Old: ov, New: nv})
+		}
+	}
+	for name, nv := range nh {
+		if _, ok := oh[name]; !ok {
+			res = append(res, HeaderDiff{Name: name, New: nv})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return strings.ToLower(res[i].Name) < strings.ToLower(res[j].Name)
+	})
+	return res
+}
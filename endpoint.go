@@ -0,0 +1,41 @@
+package harhar
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	uuidSegment = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegment  = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	numSegment  = regexp.MustCompile(`^\d+$`)
+)
+
+// TemplatePath replaces path segments which look like identifiers (numbers,
+// UUIDs, and long hex strings) with "{id}", so that requests for different
+// resources of the same kind can be grouped together.
+func TemplatePath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, s := range segs {
+		if numSegment.MatchString(s) || uuidSegment.MatchString(s) || hexSegment.MatchString(s) {
+			segs[i] = "{id}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// Endpoint returns the method, host and templated path of the entry's
+// request, e.g. "GET example.com/users/{id}". GraphQL requests include the
+// operation name, e.g. "POST example.com/graphql#GetUser".
+func (e *Entry) Endpoint() string {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return e.Request.Method + " " + e.Request.URL
+	}
+	ep := e.Request.Method + " " + u.Host + TemplatePath(u.Path)
+	if gq := e.Request.GraphQL; gq != nil && gq.OperationName != "" {
+		ep += "#" + gq.OperationName
+	}
+	return ep
+}
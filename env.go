@@ -0,0 +1,79 @@
+package harhar
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// proxy-related environment variables, as used by http.ProxyFromEnvironment
+var proxyEnvVars = []string{
+	"HTTP_PROXY", "http_proxy",
+	"HTTPS_PROXY", "https_proxy",
+	"NO_PROXY", "no_proxy",
+}
+
+// CaptureEnvironment records a fingerprint of the recording host and the
+// client configuration (Go version, OS/arch, proxy environment variables, and
+// transport settings) into the log's _environment field. It should be called
+// after the recorder's RoundTripper has been configured.
+func (c *Recorder) CaptureEnvironment() {
+	env := &Environment{
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	env.Hostname, _ = os.Hostname()
+	for _, name := range proxyEnvVars {
+		if val, ok := os.LookupEnv(name); ok {
+			env.ProxyEnv = append(env.ProxyEnv, NameValuePair{Name: name, Value: redactProxyURL(val)})
+		}
+	}
+	if tport, ok := c.RoundTripper.(*http.Transport); ok {
+		env.Transport = makeTransportInfo(tport)
+	}
+
+	c.mu.Lock()
+	c.HAR.Log.Environment = env
+	c.mu.Unlock()
+}
+
+func makeTransportInfo(t *http.Transport) *TransportInfo {
+	ti := &TransportInfo{
+		TLSHandshakeTimeout:   t.TLSHandshakeTimeout.String(),
+		IdleConnTimeout:       t.IdleConnTimeout.String(),
+		ResponseHeaderTimeout: t.ResponseHeaderTimeout.String(),
+		ExpectContinueTimeout: t.ExpectContinueTimeout.String(),
+		MaxIdleConns:          t.MaxIdleConns,
+		MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       t.MaxConnsPerHost,
+		DisableKeepAlives:     t.DisableKeepAlives,
+		DisableCompression:    t.DisableCompression,
+		HTTP2:                 t.ForceAttemptHTTP2 && t.TLSNextProto == nil,
+		UsesProxy:             t.Proxy != nil,
+	}
+	if t.TLSClientConfig != nil {
+		if t.TLSClientConfig.MinVersion != 0 {
+			ti.TLSMinVersion = tlsVersionName(t.TLSClientConfig.MinVersion)
+		}
+		if t.TLSClientConfig.MaxVersion != 0 {
+			ti.TLSMaxVersion = tlsVersionName(t.TLSClientConfig.MaxVersion)
+		}
+		ti.InsecureSkipVerify = t.TLSClientConfig.InsecureSkipVerify
+	}
+	return ti
+}
+
+// redactProxyURL removes any password from a proxy URL.
+func redactProxyURL(v string) string {
+	if !strings.Contains(v, "@") {
+		return v
+	}
+	u, err := url.Parse(v)
+	if err != nil || u.User == nil {
+		return v
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,175 @@
+package harhar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EntrySink receives entries one at a time as they are recorded, see
+// Recorder.EntrySink. It is the streaming counterpart to Sink.
+type EntrySink interface {
+	WriteEntry(e *Entry) error
+}
+
+// ExportFormat is the encoding of entry batches sent by an Exporter.
+type ExportFormat int
+
+const (
+	// ExportNDJSON sends one JSON-encoded entry per line.
+	ExportNDJSON ExportFormat = iota
+
+	// ExportHAR sends each batch as a complete HAR document.
+	ExportHAR
+)
+
+// Exporter is an EntrySink which POSTs completed entries in batches to a
+// collector endpoint, for centralizing captures from many instances. Entries
+// are queued and sent in the background, so recording never waits on the
+// network; if the queue is full, entries are dropped and counted.
+type Exporter struct {
+	// URL of the collector endpoint.
+	URL string
+
+	// Format of the batches, default ExportNDJSON.
+	Format ExportFormat
+
+	// BatchSize is the maximum number of entries per request, default 100.
+	BatchSize int
+
+	// FlushInterval is the maximum time an entry waits in the queue before
+	// being sent, default 5 seconds.
+	FlushInterval time.Duration
+
+	// Header is added to every request, e.g. for authentication.
+	Header http.Header
+
+	// Client defaults to http.DefaultClient. It should not be a client
+	// recording through the same Recorder.
+	Client *http.Client
+
+	// OnError is called with any errors sending batches, if not nil.
+	OnError func(error)
+
+	once    sync.Once
+	queue   chan Entry
+	done    chan struct{}
+	dropped uint64
+}
+
+// NewExporter returns an Exporter sending NDJSON batches to url.
+func NewExporter(url string) *Exporter {
+	return &Exporter{URL: url}
+}
+
+// WriteEntry implements EntrySink.
+func (x *Exporter) WriteEntry(e *Entry) error {
+	x.once.Do(x.start)
+	select {
+	case x.queue <- *e:
+	default:
+		atomic.AddUint64(&x.dropped, 1)
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (x *Exporter) Dropped() uint64 {
+	return atomic.LoadUint64(&x.dropped)
+}
+
+// Close sends any queued entries and stops the background sender. The
+// Exporter must not be used after Close.
+func (x *Exporter) Close() error {
+	x.once.Do(x.start)
+	close(x.queue)
+	<-x.done
+	return nil
+}
+
+func (x *Exporter) start() {
+	if x.BatchSize <= 0 {
+		x.BatchSize = 100
+	}
+	if x.FlushInterval <= 0 {
+		x.FlushInterval = 5 * time.Second
+	}
+	x.queue = make(chan Entry, x.BatchSize*10)
+	x.done = make(chan struct{})
+	go x.run()
+}
+
+func (x *Exporter) run() {
+	defer close(x.done)
+	t := time.NewTicker(x.FlushInterval)
+	defer t.Stop()
+
+	batch := make([]Entry, 0, x.BatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := x.send(batch); err != nil && x.OnError != nil {
+			x.OnError(err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case ent, ok := <-x.queue:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, ent)
+			if len(batch) >= x.BatchSize {
+				send()
+			}
+		case <-t.C:
+			send()
+		}
+	}
+}
+
+func (x *Exporter) send(batch []Entry) error {
+	buf := &bytes.Buffer{}
+	contentType := "application/x-ndjson"
+	if x.Format == ExportHAR {
+		contentType = "application/json"
+		h := NewHAR("harhar-exporter")
+		h.Log.Entries = batch
+		if _, err := h.WriteTo(buf); err != nil {
+			return err
+		}
+	} else if err := writeNDJSON(buf, batch); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, x.URL, buf)
+	if err != nil {
+		return err
+	}
+	for name, vals := range x.Header {
+		req.Header[name] = vals
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	cli := x.Client
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("harhar: export to %s failed: %s", x.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package harhar
+
+import "net/http"
+
+// makeFetchMetadata extracts the fetch metadata and priority request headers
+// sent by browsers, or returns nil if there are none.
+func makeFetchMetadata(h http.Header) *FetchMetadata {
+	fm := &FetchMetadata{
+		Site:     h.Get("Sec-Fetch-Site"),
+		Mode:     h.Get("Sec-Fetch-Mode"),
+		Dest:     h.Get("Sec-Fetch-Dest"),
+		User:     h.Get("Sec-Fetch-User") == "?1",
+		Priority: h.Get("Priority"),
+	}
+	if *fm == (FetchMetadata{}) {
+		return nil
+	}
+	return fm
+}
+
+// FetchSummary counts requests by their fetch metadata.
+type FetchSummary struct {
+	// Total number of entries with fetch metadata
+	Total int
+
+	// counts keyed by header value ("" if not sent)
+	BySite     map[string]int
+	ByMode     map[string]int
+	ByDest     map[string]int
+	ByPriority map[string]int
+
+	// UserInitiated counts navigations triggered by user activation
+	UserInitiated int
+}
+
+// SummarizeFetchMetadata counts the entries of h by their fetch metadata,
+// e.g. to see how many cross-site requests were made, or which resource
+// types were loaded at high priority.
+func SummarizeFetchMetadata(h *HAR) FetchSummary {
+	fs := FetchSummary{
+		BySite:     make(map[string]int),
+		ByMode:     make(map[string]int),
+		ByDest:     make(map[string]int),
+		ByPriority: make(map[string]int),
+	}
+	for _, ent := range h.Log.Entries {
+		fm := ent.Request.FetchMetadata
+		if fm == nil {
+			continue
+		}
+		fs.Total++
+		fs.BySite[fm.Site]++
+		fs.ByMode[fm.Mode]++
+		fs.ByDest[fm.Dest]++
+		fs.ByPriority[fm.Priority]++
+		if fm.User {
+			fs.UserInitiated++
+		}
+	}
+	return fs
+}
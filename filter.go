@@ -0,0 +1,138 @@
+package harhar
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterOptions selects entries for Filter. Each non-empty field must match;
+// within a list field, any one item may match.
+type FilterOptions struct {
+	// Hosts are glob patterns matched against the request host (without
+	// port), e.g. "*.example.com".
+	Hosts []string
+
+	// Path is matched against the request URL path.
+	Path *regexp.Regexp
+
+	// Methods are request methods, e.g. "GET" (case-insensitive).
+	Methods []string
+
+	// Statuses are response status codes, or classes written as "4xx".
+	Statuses []string
+
+	// MIMETypes are glob patterns matched against the response MIME type
+	// (without parameters), e.g. "application/json" or "image/*".
+	MIMETypes []string
+
+	// Since and Until limit the request start time, if non-zero. Since is
+	// inclusive and Until exclusive.
+	Since, Until time.Time
+
+	// Tags, if set, requires entries to have at least one of the tags.
+	Tags []string
+}
+
+// Match reports whether the entry is selected by the options.
+func (o *FilterOptions) Match(e *Entry) bool {
+	if len(o.Hosts) > 0 || o.Path != nil {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return false
+		}
+		if len(o.Hosts) > 0 && !matchAnyGlob(o.Hosts, strings.ToLower(u.Hostname())) {
+			return false
+		}
+		if o.Path != nil && !o.Path.MatchString(u.Path) {
+			return false
+		}
+	}
+	if len(o.Methods) > 0 && !containsFold(o.Methods, e.Request.Method) {
+		return false
+	}
+	if len(o.Statuses) > 0 && !matchStatus(o.Statuses, e.Response.StatusCode) {
+		return false
+	}
+	if len(o.MIMETypes) > 0 {
+		mt := e.Response.Body.MIMEType
+		if parsed, _, err := mime.ParseMediaType(mt); err == nil {
+			mt = parsed
+		}
+		if !matchAnyGlob(o.MIMETypes, strings.ToLower(mt)) {
+			return false
+		}
+	}
+	if !o.Since.IsZero() || !o.Until.IsZero() {
+		start := e.StartTime()
+		if !o.Since.IsZero() && start.Before(o.Since) {
+			return false
+		}
+		if !o.Until.IsZero() && !start.Before(o.Until) {
+			return false
+		}
+	}
+	if len(o.Tags) > 0 && !e.hasAnyTag(o.Tags) {
+		return false
+	}
+	return true
+}
+
+// Filter returns a copy of the archive containing only the entries matched
+// by opts. Pages are kept if any of their entries are.
+func Filter(h *HAR, opts FilterOptions) *HAR {
+	res := &HAR{Log: h.Log}
+	res.Log.Entries = nil
+	res.Log.Pages = nil
+	pages := make(map[string]bool)
+	for i := range h.Log.Entries {
+		if opts.Match(&h.Log.Entries[i]) {
+			res.Log.Entries = append(res.Log.Entries, h.Log.Entries[i])
+			pages[h.Log.Entries[i].PageRef] = true
+		}
+	}
+	for _, pg := range h.Log.Pages {
+		if pages[pg.ID] {
+			res.Log.Pages = append(res.Log.Pages, pg)
+		}
+	}
+	return res
+}
+
+// matchAnyGlob reports whether s matches any of the (lowercased) patterns.
+func matchAnyGlob(patterns []string, s string) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pat), s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStatus reports whether code matches any of the codes or "Nxx" classes.
+func matchStatus(statuses []string, code int) bool {
+	for _, s := range statuses {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if len(s) == 3 && strings.HasSuffix(s, "xx") {
+			if int(s[0]-'0') == code/100 {
+				return true
+			}
+		} else if s == strconv.Itoa(code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package harhar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// headers which are managed by HTTP clients and servers themselves, and so
+// omitted from generated code
+var managedHeaders = map[string]bool{
+	"Host": true, "Content-Length": true, "Accept-Encoding": true, "Connection": true,
+	"Transfer-Encoding": true, "Date": true, "Content-Encoding": true,
+}
+
+type goTest struct {
+	Name       string
+	Method     string
+	RequestURI string
+	ReqHeaders []NameValuePair
+	ReqBody    string
+	Status     int
+	Headers    []NameValuePair
+	Body       string
+}
+
+var goTestTemplate = template.Must(template.New("gotest").Funcs(template.FuncMap{
+	"quote": goQuote,
+}).Parse(`// Code generated by harhar gen-test from a recorded HAR; edit as needed.
+
+package {{.Package}}
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+{{- if .UsesStrings}}
+	"strings"
+{{- end}}
+	"testing"
+)
+{{range .Tests}}
+func {{.Name}}(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != {{quote .Method}} || r.URL.RequestURI() != {{quote .RequestURI}} {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.RequestURI())
+		}
+{{- range .Headers}}
+		w.Header().Add({{quote .Name}}, {{quote .Value}})
+{{- end}}
+		w.WriteHeader({{.Status}})
+		io.WriteString(w, {{quote .Body}})
+	}))
+	defer srv.Close()
+
+	{{if .ReqBody}}req, err := http.NewRequest({{quote .Method}}, srv.URL+{{quote .RequestURI}}, strings.NewReader({{quote .ReqBody}})){{else}}req, err := http.NewRequest({{quote .Method}}, srv.URL+{{quote .RequestURI}}, nil){{end}}
+	if err != nil {
+		t.Fatal(err)
+	}
+{{- range .ReqHeaders}}
+	req.Header.Add({{quote .Name}}, {{quote .Value}})
+{{- end}}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != {{.Status}} {
+		t.Errorf("status = %d, want {{.Status}}", resp.StatusCode)
+	}
+	if want := {{quote .Body}}; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+{{end}}`))
+
+// WriteGoTest writes a Go test file to w, in package pkg, with a test for
+// each of the entries at indexes (or every entry, if none are given). Each
+// test serves the recorded response from an httptest.Server and sends the
+// recorded request to it, as a starting point for reproducing a captured
+// exchange in a unit test.
+func (h *HAR) WriteGoTest(w io.Writer, pkg string, indexes ...int) error {
+	if len(indexes) == 0 {
+		for i := range h.Log.Entries {
+			indexes = append(indexes, i)
+		}
+	}
+
+	names := make(map[string]int)
+	var tests []goTest
+	for _, i := range indexes {
+		if i < 0 || i >= len(h.Log.Entries) {
+			return fmt.Errorf("harhar: invalid entry index %d", i)
+		}
+		ent := &h.Log.Entries[i]
+		req, err := ent.Request.ToHTTPRequest(context.Background())
+		if err != nil {
+			return fmt.Errorf("harhar: entry %d: %w", i, err)
+		}
+		resp, err := ent.Response.ToHTTPResponse()
+		if err != nil {
+			return fmt.Errorf("harhar: entry %d: %w", i, err)
+		}
+
+		t := goTest{
+			Name:       goTestName(req.Method, req.URL.Path),
+			Method:     req.Method,
+			RequestURI: req.URL.RequestURI(),
+			ReqHeaders: goTestHeaders(req.Header),
+			Status:     resp.StatusCode,
+			Headers:    goTestHeaders(resp.Header),
+		}
+		if n := names[t.Name]; n > 0 {
+			names[t.Name]++
+			t.Name += strconv.Itoa(n + 1)
+		} else {
+			names[t.Name] = 1
+		}
+		if req.Body != nil {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return err
+			}
+			t.ReqBody = string(body)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		t.Body = string(body)
+		tests = append(tests, t)
+	}
+
+	usesStrings := false
+	for _, t := range tests {
+		usesStrings = usesStrings || t.ReqBody != ""
+	}
+	var buf bytes.Buffer
+	err := goTestTemplate.Execute(&buf, struct {
+		Package     string
+		UsesStrings bool
+		Tests       []goTest
+	}{pkg, usesStrings, tests})
+	if err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goTestName returns a test function name like TestGetApiUsersId for the
+// method and path.
+func goTestName(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString("Test")
+	for _, word := range strings.FieldsFunc(method+"/"+TemplatePath(path), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		sb.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+	}
+	return sb.String()
+}
+
+// goTestHeaders returns the headers in name order, skipping managedHeaders.
+func goTestHeaders(h http.Header) []NameValuePair {
+	var res []NameValuePair
+	for name, vals := range h {
+		if managedHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for _, v := range vals {
+			res = append(res, NameValuePair{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// goQuote returns s as a Go string literal, preferring a raw string for
+// readable multi-line text and JSON.
+func goQuote(s string) string {
+	if strings.ContainsAny(s, "\n\"\\") && !strings.ContainsAny(s, "`\r") && utf8.ValidString(s) && strings.IndexFunc(s, func(r rune) bool {
+		return r != '\n' && r != '\t' && !unicode.IsPrint(r)
+	}) < 0 {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
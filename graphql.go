@@ -0,0 +1,49 @@
+package harhar
+
+import (
+	"encoding/json"
+	"mime"
+	"strings"
+)
+
+// makeGraphQL parses a JSON request body as a GraphQL request, or returns nil
+// if it does not look like one.
+func makeGraphQL(mimeType, body string) *GraphQL {
+	if mt, _, err := mime.ParseMediaType(mimeType); err != nil || (mt != "application/json" && mt != "application/graphql+json") {
+		return nil
+	}
+	var req struct {
+		Query         string          `json:"query"`
+		OperationName string          `json:"operationName"`
+		Variables     json.RawMessage `json:"variables"`
+	}
+	if err := json.Unmarshal([]byte(body), &req); err != nil || req.Query == "" {
+		return nil
+	}
+	gq := &GraphQL{
+		OperationName: req.OperationName,
+		OperationType: graphQLOperationType(req.Query),
+	}
+	if len(req.Variables) > 0 && string(req.Variables) != "null" {
+		gq.Variables = req.Variables
+	}
+	return gq
+}
+
+// graphQLOperationType returns the type of the first operation in a GraphQL
+// document. Queries may omit the keyword ("{ ... }").
+func graphQLOperationType(query string) string {
+	for _, line := range strings.Split(query, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, op := range []string{"query", "mutation", "subscription"} {
+			if strings.HasPrefix(line, op) {
+				return op
+			}
+		}
+		break
+	}
+	return "query"
+}
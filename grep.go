@@ -0,0 +1,111 @@
+package harhar
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GrepScope selects which parts of an entry Grep searches.
+type GrepScope int
+
+const (
+	// GrepURL searches request URLs.
+	GrepURL GrepScope = 1 << iota
+	// GrepHeaders searches request and response header names and values.
+	GrepHeaders
+	// GrepBodies searches request and response bodies.
+	GrepBodies
+
+	// GrepAll searches everything.
+	GrepAll = GrepURL | GrepHeaders | GrepBodies
+)
+
+// GrepMatch describes a single match found by Grep.
+type GrepMatch struct {
+	// Entry is the index of the matching entry.
+	Entry int
+	// Field names where the match was found, e.g. "url",
+	// "request.header Authorization" or "response.body".
+	Field string
+	// Text is the matching text with surrounding context.
+	Text string
+}
+
+// Grep searches the archive's entries for re, returning every match within
+// scope. Each match includes up to context bytes of surrounding text. Base64
+// encoded bodies are not searched.
+func Grep(h *HAR, re *regexp.Regexp, scope GrepScope, context int) []GrepMatch {
+	var res []GrepMatch
+	for i := range h.Log.Entries {
+		res = append(res, GrepEntry(i, &h.Log.Entries[i], re, scope, context)...)
+	}
+	return res
+}
+
+// GrepEntry searches a single entry as Grep does, e.g. for entries read with
+// Stream. Matches are reported with the given entry index i.
+func GrepEntry(i int, ent *Entry, re *regexp.Regexp, scope GrepScope, context int) []GrepMatch {
+	var res []GrepMatch
+	search := func(field, s string) {
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			res = append(res, GrepMatch{Entry: i, Field: field, Text: excerpt(s, loc[0], loc[1], context)})
+		}
+	}
+	if scope&GrepURL != 0 {
+		search("url", ent.Request.URL)
+	}
+	if scope&GrepHeaders != 0 {
+		for _, p := range ent.Request.Headers {
+			search("request.header "+p.Name, p.Name+": "+p.Value)
+		}
+		for _, p := range ent.Response.Headers {
+			search("response.header "+p.Name, p.Name+": "+p.Value)
+		}
+	}
+	if scope&GrepBodies != 0 {
+		if ent.Request.Body.Encoding == "" {
+			search("request.body", ent.Request.Body.Text())
+		}
+		for _, p := range ent.Request.Body.Params {
+			search("request.param "+p.Name, p.Value)
+		}
+		if ent.Response.Body.Encoding == "" {
+			search("response.body", ent.Response.Body.Text())
+		}
+	}
+	return res
+}
+
+// excerpt returns s[start:end] with up to n bytes of context on either side,
+// cut at line boundaries and marked with "..." where truncated.
+func excerpt(s string, start, end, n int) string {
+	from, to := start-n, end+n
+	if from < 0 {
+		from = 0
+	}
+	if to > len(s) {
+		to = len(s)
+	}
+	if j := strings.LastIndexByte(s[from:start], '\n'); j >= 0 {
+		from += j + 1
+	}
+	if j := strings.IndexByte(s[end:to], '\n'); j >= 0 {
+		to = end + j
+	}
+	// don't split multi-byte characters
+	for from > 0 && from < start && s[from]&0xC0 == 0x80 {
+		from++
+	}
+	for to < len(s) && to > end && s[to]&0xC0 == 0x80 {
+		to--
+	}
+
+	text := s[from:to]
+	if from > 0 {
+		text = "..." + text
+	}
+	if to < len(s) {
+		text += "..."
+	}
+	return text
+}
@@ -0,0 +1,81 @@
+package harhar
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// ReadFile loads an HTTP Archive document from filename.
+func ReadFile(filename string) (*HAR, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Decode reads an HTTP Archive document from r. Gzip-compressed documents
+// are decompressed transparently.
+func Decode(r io.Reader) (*HAR, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	h := &HAR{}
+	err = json.NewDecoder(r).Decode(h)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// decompress returns a reader for the contents of r, which are decompressed
+// if they begin with the gzip magic number.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// WriteFile writes the HAR log format to the filename given, then returns the
+// number of bytes.
+func (h *HAR) WriteFile(filename string) (int, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(f)
+	n, err := h.WriteTo(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return int(n), err
+}
+
+// WriteTo implements io.WriterTo, encoding the HAR as JSON one entry at a
+// time so that the whole document is never held in memory.
+func (h *HAR) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := h.encode(cw, nil)
+	return cw.n, err
+}
+
+// StartTime parses the Start timestamp of the entry. A zero time is returned
+// if it cannot be parsed.
+func (e *Entry) StartTime() time.Time {
+	t, err := time.Parse(time.RFC3339Nano, e.Start)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
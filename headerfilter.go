@@ -0,0 +1,31 @@
+package harhar
+
+import "strings"
+
+// filterHeaders drops headers according to IncludeHeaders and ExcludeHeaders.
+func (c *Recorder) filterHeaders(ent *Entry) {
+	if len(c.IncludeHeaders) == 0 && len(c.ExcludeHeaders) == 0 {
+		return
+	}
+	ent.Request.Headers = filterPairsBy(ent.Request.Headers, c.keepHeader)
+	ent.Response.Headers = filterPairsBy(ent.Response.Headers, c.keepHeader)
+	if !c.keepHeader("Cookie") {
+		ent.Request.Cookies = []Cookie{}
+	}
+	if !c.keepHeader("Set-Cookie") {
+		ent.Response.Cookies = []Cookie{}
+	}
+}
+
+// keepHeader reports whether the named header passes the recorder's filters.
+func (c *Recorder) keepHeader(name string) bool {
+	if len(c.IncludeHeaders) > 0 && !matchHeader(c.IncludeHeaders, name) {
+		return false
+	}
+	return !matchHeader(c.ExcludeHeaders, name)
+}
+
+// matchHeader reports whether name matches any of the glob patterns.
+func matchHeader(patterns []string, name string) bool {
+	return matchAnyGlob(patterns, strings.ToLower(name))
+}
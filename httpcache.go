@@ -0,0 +1,408 @@
+package harhar
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// default CachingTransport.MaxEntrySize
+const defaultMaxCacheEntry = 10 << 20
+
+// CachingTransport is an http.RoundTripper which keeps responses in memory as
+// a shared HTTP cache would, honoring Cache-Control, Expires, Vary, ETag and
+// Last-Modified. Fresh responses are served without contacting the server,
+// and stale ones are revalidated with conditional requests. Only GET requests
+// are answered from the cache, and other requests for a URL remove it.
+//
+// When used beneath a Recorder, either as its RoundTripper or as the
+// transport of a handler it records, the recorded Entry.Cache describes the
+// cache entry before and after each request.
+type CachingTransport struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	size    int64
+
+	// Next sends the requests which are not answered from the cache.
+	Next http.RoundTripper
+
+	// MaxEntrySize is the largest response body stored, 10MB if 0.
+	MaxEntrySize int64
+
+	// MaxSize limits the total size of the stored bodies, evicting the
+	// least recently used. 0 means no limit.
+	MaxSize int64
+
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// NewCachingTransport returns a CachingTransport in front of next, or
+// http.DefaultTransport if next is nil.
+func NewCachingTransport(next http.RoundTripper) *CachingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &CachingTransport{Next: next}
+}
+
+// cacheEntry is a stored response.
+type cacheEntry struct {
+	status int
+	proto  string
+	header http.Header
+	body   []byte
+
+	// values of the request headers named by Vary
+	vary http.Header
+
+	stored     time.Time
+	initialAge time.Duration
+	lifetime   time.Duration
+	lastAccess time.Time
+	hits       int
+}
+
+// cacheSlot carries the cache state of a request from a CachingTransport to
+// the Recorder recording it.
+type cacheSlot struct {
+	state CacheState
+	set   bool
+}
+
+type cacheSlotKey struct{}
+
+func withCacheSlot(ctx context.Context) (context.Context, *cacheSlot) {
+	slot := &cacheSlot{}
+	return context.WithValue(ctx, cacheSlotKey{}, slot), slot
+}
+
+func (t *CachingTransport) now() time.Time {
+	if t.Clock != nil {
+		return t.Clock()
+	}
+	return time.Now()
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.String()
+	if req.Method != http.MethodGet {
+		if req.Method != http.MethodHead && req.Method != http.MethodOptions && req.Method != http.MethodTrace {
+			// unsafe methods invalidate what is stored for the URL
+			t.mu.Lock()
+			t.remove(key)
+			t.mu.Unlock()
+		}
+		return t.Next.RoundTrip(req)
+	}
+
+	slot, _ := req.Context().Value(cacheSlotKey{}).(*cacheSlot)
+	if slot == nil {
+		slot = &cacheSlot{}
+	}
+	slot.set = true
+	now := t.now()
+	reqCC := parseCacheControl(req.Header.Get("Cache-Control"))
+
+	t.mu.Lock()
+	ce := t.entries[key]
+	if ce != nil && !ce.matches(req) {
+		ce = nil
+	}
+	etag, lastModified := "", ""
+	if ce != nil {
+		slot.state.Before = ce.info()
+		etag, lastModified = ce.header.Get("ETag"), ce.header.Get("Last-Modified")
+		if ce.fresh(now) && !requiresValidation(req, reqCC) {
+			ce.hits++
+			ce.lastAccess = now
+			slot.state.After = ce.info()
+			resp := ce.response(req, now)
+			t.mu.Unlock()
+			return resp, nil
+		}
+	}
+	t.mu.Unlock()
+
+	// revalidate a stale entry, unless the client is validating its own copy
+	out := req
+	clientConditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	if (etag != "" || lastModified != "") && !clientConditional {
+		out = req.Clone(req.Context())
+		if etag != "" {
+			out.Header.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			out.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.Next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && out == req {
+		// answers the client's own validators, the entry is unchanged
+		slot.state.After = slot.state.Before
+		return resp, nil
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		ce.revalidated(resp, now)
+		ce.hits++
+		slot.state.After = ce.info()
+		return ce.response(req, now), nil
+	}
+
+	if _, ok := reqCC["no-store"]; ok || !storable(req, resp) {
+		t.mu.Lock()
+		t.remove(key)
+		t.mu.Unlock()
+		return resp, nil
+	}
+
+	limit := t.MaxEntrySize
+	if limit <= 0 {
+		limit = defaultMaxCacheEntry
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		// too large to store, so drop the previous response and pass the
+		// rest through
+		t.mu.Lock()
+		t.remove(key)
+		t.mu.Unlock()
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	ce = &cacheEntry{
+		status:     resp.StatusCode,
+		proto:      resp.Proto,
+		header:     resp.Header.Clone(),
+		body:       body,
+		vary:       varyValues(req, resp.Header),
+		stored:     now,
+		initialAge: headerAge(resp.Header),
+		lifetime:   freshnessLifetime(resp.Header, now),
+		lastAccess: now,
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(key)
+	if t.entries == nil {
+		t.entries = make(map[string]*cacheEntry)
+	}
+	t.entries[key] = ce
+	t.size += int64(len(body))
+	t.evict(key)
+	slot.state.After = ce.info()
+	return resp, nil
+}
+
+// remove deletes the entry for key, if any. t.mu must be held.
+func (t *CachingTransport) remove(key string) {
+	if ce, ok := t.entries[key]; ok {
+		t.size -= int64(len(ce.body))
+		delete(t.entries, key)
+	}
+}
+
+// evict removes the least recently used entries other than keep until the
+// cache fits in MaxSize. t.mu must be held.
+func (t *CachingTransport) evict(keep string) {
+	for t.MaxSize > 0 && t.size > t.MaxSize && len(t.entries) > 1 {
+		oldest := ""
+		for key, ce := range t.entries {
+			if key != keep && (oldest == "" || ce.lastAccess.Before(t.entries[oldest].lastAccess)) {
+				oldest = key
+			}
+		}
+		t.remove(oldest)
+	}
+}
+
+// requiresValidation reports whether the client asked for a response
+// validated with the server.
+func requiresValidation(req *http.Request, reqCC map[string]string) bool {
+	if _, ok := reqCC["no-cache"]; ok {
+		return true
+	}
+	if v, ok := reqCC["max-age"]; ok && v == "0" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(req.Header.Get("Pragma")), "no-cache")
+}
+
+// storable reports whether a shared cache may store resp.
+func storable(req *http.Request, resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect,
+		http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone:
+	default:
+		return false
+	}
+	cc := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	if _, ok := cc["private"]; ok {
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" {
+		// only if the response explicitly allows it
+		_, public := cc["public"]
+		_, shared := cc["s-maxage"]
+		_, revalidate := cc["must-revalidate"]
+		if !public && !shared && !revalidate {
+			return false
+		}
+	}
+	_, hasMaxAge := cc["max-age"]
+	_, hasShared := cc["s-maxage"]
+	return hasMaxAge || hasShared || resp.Header.Get("Expires") != "" ||
+		resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// freshnessLifetime returns how long a response stays fresh: s-maxage, then
+// max-age, then Expires, then a tenth of the time since Last-Modified (at
+// most a day), as a shared cache would compute it.
+func freshnessLifetime(h http.Header, now time.Time) time.Duration {
+	cc := parseCacheControl(h.Get("Cache-Control"))
+	if _, ok := cc["no-cache"]; ok {
+		return 0
+	}
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[name]; ok {
+			secs, err := strconv.Atoi(v)
+			if err != nil || secs < 0 {
+				return 0
+			}
+			return time.Duration(secs) * time.Second
+		}
+	}
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		date = now
+	}
+	if v := h.Get("Expires"); v != "" {
+		exp, err := http.ParseTime(v)
+		if err != nil || exp.Before(date) {
+			return 0
+		}
+		return exp.Sub(date)
+	}
+	if lm, err := http.ParseTime(h.Get("Last-Modified")); err == nil && lm.Before(date) {
+		d := date.Sub(lm) / 10
+		if d > 24*time.Hour {
+			d = 24 * time.Hour
+		}
+		return d
+	}
+	return 0
+}
+
+// headerAge returns the value of the Age header.
+func headerAge(h http.Header) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(h.Get("Age")))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// varyValues returns the request headers named by the Vary header of a
+// response.
+func varyValues(req *http.Request, h http.Header) http.Header {
+	vary := make(http.Header)
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				vary[http.CanonicalHeaderKey(name)] = req.Header.Values(name)
+			}
+		}
+	}
+	return vary
+}
+
+// matches reports whether req has the same values for the Vary headers as
+// the request the entry was stored for.
+func (ce *cacheEntry) matches(req *http.Request) bool {
+	for name, vals := range ce.vary {
+		if strings.Join(req.Header.Values(name), ",") != strings.Join(vals, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func (ce *cacheEntry) age(now time.Time) time.Duration {
+	return ce.initialAge + now.Sub(ce.stored)
+}
+
+func (ce *cacheEntry) fresh(now time.Time) bool {
+	return ce.age(now) < ce.lifetime
+}
+
+// revalidated updates the entry from a 304 Not Modified response.
+func (ce *cacheEntry) revalidated(resp *http.Response, now time.Time) {
+	for name, vals := range resp.Header {
+		if name == "Content-Length" {
+			continue
+		}
+		ce.header[name] = vals
+	}
+	ce.stored = now
+	ce.lastAccess = now
+	ce.initialAge = headerAge(resp.Header)
+	ce.lifetime = freshnessLifetime(ce.header, now)
+}
+
+// response returns the stored response for req.
+func (ce *cacheEntry) response(req *http.Request, now time.Time) *http.Response {
+	resp := &http.Response{
+		Status:        strconv.Itoa(ce.status) + " " + http.StatusText(ce.status),
+		StatusCode:    ce.status,
+		Proto:         ce.proto,
+		Header:        ce.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(ce.body)),
+		ContentLength: int64(len(ce.body)),
+		Request:       req,
+	}
+	resp.ProtoMajor, resp.ProtoMinor, _ = http.ParseHTTPVersion(ce.proto)
+	resp.Header.Set("Age", strconv.Itoa(int(ce.age(now)/time.Second)))
+	return resp
+}
+
+// info describes the entry for Entry.Cache.
+func (ce *cacheEntry) info() *CacheInfo {
+	return &CacheInfo{
+		Expires:      ce.stored.Add(ce.lifetime - ce.initialAge).Format(time.RFC3339Nano),
+		LastAccess:   ce.lastAccess.Format(time.RFC3339Nano),
+		ETag:         ce.header.Get("ETag"),
+		HitCount:     ce.hits,
+		LastModified: formatHTTPDate(ce.header.Get("Last-Modified")),
+	}
+}
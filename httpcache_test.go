@@ -0,0 +1,144 @@
+package harhar
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFreshnessLifetime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Format(http.TimeFormat)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"max-age", http.Header{"Cache-Control": {"max-age=60"}}, time.Minute},
+		{"s-maxage first", http.Header{"Cache-Control": {"max-age=60, s-maxage=120"}}, 2 * time.Minute},
+		{"bad max-age", http.Header{"Cache-Control": {"max-age=soon"}}, 0},
+		{"negative max-age", http.Header{"Cache-Control": {"max-age=-1"}}, 0},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache, max-age=60"}}, 0},
+		{"max-age over expires", http.Header{
+			"Cache-Control": {"max-age=60"},
+			"Date":          {date},
+			"Expires":       {now.Add(time.Hour).Format(http.TimeFormat)},
+		}, time.Minute},
+		{"expires", http.Header{
+			"Date":    {date},
+			"Expires": {now.Add(time.Hour).Format(http.TimeFormat)},
+		}, time.Hour},
+		{"expires without date", http.Header{
+			"Expires": {now.Add(time.Hour).Format(http.TimeFormat)},
+		}, time.Hour},
+		{"expired", http.Header{
+			"Date":    {date},
+			"Expires": {now.Add(-time.Hour).Format(http.TimeFormat)},
+		}, 0},
+		{"bad expires", http.Header{"Date": {date}, "Expires": {"0"}}, 0},
+		{"last-modified", http.Header{
+			"Date":          {date},
+			"Last-Modified": {now.Add(-10 * time.Hour).Format(http.TimeFormat)},
+		}, time.Hour},
+		{"last-modified at most a day", http.Header{
+			"Date":          {date},
+			"Last-Modified": {now.Add(-100 * 24 * time.Hour).Format(http.TimeFormat)},
+		}, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := freshnessLifetime(tt.header, now); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCachingTransportFreshness(t *testing.T) {
+	type step struct {
+		after      time.Duration // since the first request
+		reqCC      string        // request Cache-Control
+		header     http.Header   // sent by the server if contacted
+		body       string
+		wantServed bool // by the server, not the cache
+		wantBody   string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "fresh then stale",
+			steps: []step{
+				{0, "", http.Header{"Cache-Control": {"max-age=60"}}, "a", true, "a"},
+				{30 * time.Second, "", nil, "", false, "a"},
+				{61 * time.Second, "", http.Header{"Cache-Control": {"max-age=60"}}, "b", true, "b"},
+				{90 * time.Second, "", nil, "", false, "b"},
+			},
+		},
+		{
+			name: "age counts against freshness",
+			steps: []step{
+				{0, "", http.Header{"Cache-Control": {"max-age=60"}, "Age": {"50"}}, "a", true, "a"},
+				{5 * time.Second, "", nil, "", false, "a"},
+				{11 * time.Second, "", http.Header{"Cache-Control": {"max-age=60"}}, "b", true, "b"},
+			},
+		},
+		{
+			name: "no-store",
+			steps: []step{
+				{0, "", http.Header{"Cache-Control": {"no-store"}}, "a", true, "a"},
+				{time.Second, "", http.Header{"Cache-Control": {"no-store"}}, "b", true, "b"},
+			},
+		},
+		{
+			name: "replacement too large to store",
+			steps: []step{
+				{0, "", http.Header{"Cache-Control": {"max-age=60"}}, "a", true, "a"},
+				{time.Second, "no-cache", http.Header{"Cache-Control": {"max-age=60"}}, strings.Repeat("b", 20), true, strings.Repeat("b", 20)},
+				{2 * time.Second, "", http.Header{"Cache-Control": {"max-age=60"}}, "c", true, "c"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			now := start
+			var cur step
+			served := false
+			ct := NewCachingTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				served = true
+				rec := httptest.NewRecorder()
+				for name, vals := range cur.header {
+					rec.Header()[name] = vals
+				}
+				io.WriteString(rec, cur.body)
+				return rec.Result(), nil
+			}))
+			ct.Clock = func() time.Time { return now }
+			ct.MaxEntrySize = 10
+
+			for i, st := range tt.steps {
+				now, cur, served = start.Add(st.after), st, false
+				req := httptest.NewRequest("GET", "http://example.com/", nil)
+				if st.reqCC != "" {
+					req.Header.Set("Cache-Control", st.reqCC)
+				}
+				resp, err := ct.RoundTrip(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if served != st.wantServed {
+					t.Errorf("step %d: served by the server is %v, want %v", i, served, st.wantServed)
+				}
+				if string(body) != st.wantBody {
+					t.Errorf("step %d: got body %q, want %q", i, body, st.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package harhar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// EntryFromRecorder converts a request and the httptest.ResponseRecorder it
+// was served into as an entry, so existing handler tests can emit HAR data.
+// d is the time the handler took, and the entry is assumed to have just
+// finished. req's body must not have been consumed by the handler if it is
+// to be included.
+func EntryFromRecorder(req *http.Request, rr *httptest.ResponseRecorder, d time.Duration) (Entry, error) {
+	var err error
+	ent := Entry{}
+	ent.Request, err = makeRequest(req, false)
+	if err != nil {
+		return ent, err
+	}
+
+	resp := rr.Result()
+	if req.Proto != "" {
+		resp.Proto = req.Proto
+	}
+	ent.Response, err = makeResponse(resp, time.Now())
+	if err != nil {
+		return ent, err
+	}
+
+	startTime := time.Now().Add(-d)
+	ent.Start = startTime.Format(time.RFC3339Nano)
+	ent.Time = int(d.Milliseconds())
+	ent.Timings.Send = -1
+	ent.Timings.Wait = ent.Time
+	ent.Timings.Receive = -1
+	ent.Cache = makeCache(req, resp, startTime)
+	return ent, nil
+}
@@ -0,0 +1,242 @@
+package harhar
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrStaleIndex is returned by ReadIndex when the sidecar index does not
+// describe the current contents of the HAR file.
+var ErrStaleIndex = errors.New("harhar: index does not match HAR file")
+
+// Index is a sidecar for a HAR file which locates each entry by byte offset,
+// along with the fields most commonly used to select entries. Queries can
+// consult the index and decode only the matching entries instead of parsing
+// the entire archive.
+type Index struct {
+	// Size and modification time of the indexed HAR file. A file rewritten
+	// with the same size (e.g. by equal-length replacements) is still
+	// detected as changed by its modification time.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+
+	// Entries in the same order as the HAR file.
+	Entries []IndexEntry `json:"entries"`
+}
+
+// IndexEntry locates one entry within a HAR file.
+type IndexEntry struct {
+	// Offset and Length of the entry's JSON object within the file.
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+
+	Start  string `json:"start"`
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+}
+
+// IndexFilename returns the name of the sidecar index for a HAR file.
+func IndexFilename(filename string) string {
+	return filename + ".idx"
+}
+
+// WriteFileIndexed writes the HAR to filename along with a sidecar index (see
+// IndexFilename), then returns the number of bytes in the HAR file.
+func (h *HAR) WriteFileIndexed(filename string) (int, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(f)
+	cw := &countingWriter{w: bw}
+	ix := &Index{}
+	err = h.encode(cw, func(off, n int64, e *Entry) {
+		ie := IndexEntry{
+			Offset: off,
+			Length: n,
+			Start:  e.Start,
+			Method: e.Request.Method,
+			Status: e.Response.StatusCode,
+		}
+		if u, err := url.Parse(e.Request.URL); err == nil {
+			ie.Host = u.Host
+			ie.Path = u.Path
+		}
+		ix.Entries = append(ix.Entries, ie)
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return int(cw.n), err
+	}
+	st, err := os.Stat(filename)
+	if err != nil {
+		return int(cw.n), err
+	}
+	ix.Size, ix.ModTime = st.Size(), st.ModTime()
+
+	idx, err := json.Marshal(ix)
+	if err != nil {
+		return int(cw.n), err
+	}
+	return int(cw.n), os.WriteFile(IndexFilename(filename), idx, 0644)
+}
+
+// ReadIndex loads the sidecar index for the HAR file filename. If the index
+// does not exist, an error satisfying errors.Is(err, fs.ErrNotExist) is
+// returned. If it is out of date, ErrStaleIndex is returned.
+func ReadIndex(filename string) (*Index, error) {
+	data, err := os.ReadFile(IndexFilename(filename))
+	if err != nil {
+		return nil, err
+	}
+	ix := &Index{}
+	if err = json.Unmarshal(data, ix); err != nil {
+		return nil, err
+	}
+	st, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() != ix.Size || !st.ModTime().Equal(ix.ModTime) {
+		return nil, ErrStaleIndex
+	}
+	return ix, nil
+}
+
+// ReadEntry decodes the i-th entry from the HAR file contents r.
+func (ix *Index) ReadEntry(r io.ReaderAt, i int) (Entry, error) {
+	ie := ix.Entries[i]
+	data := make([]byte, ie.Length)
+	var ent Entry
+	if _, err := r.ReadAt(data, ie.Offset); err != nil {
+		return ent, err
+	}
+	err := json.Unmarshal(data, &ent)
+	return ent, err
+}
+
+// Select returns the positions of the entries whose indexed host, path,
+// method and status are matched by opts. The other options are not indexed,
+// so the decoded entries must still be checked with opts.Match.
+func (ix *Index) Select(opts FilterOptions) []int {
+	var res []int
+	for i, ie := range ix.Entries {
+		if len(opts.Hosts) > 0 {
+			host := ie.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if !matchAnyGlob(opts.Hosts, strings.ToLower(strings.Trim(host, "[]"))) {
+				continue
+			}
+		}
+		if opts.Path != nil && !opts.Path.MatchString(ie.Path) {
+			continue
+		}
+		if len(opts.Methods) > 0 && !containsFold(opts.Methods, ie.Method) {
+			continue
+		}
+		if len(opts.Statuses) > 0 && !matchStatus(opts.Statuses, ie.Status) {
+			continue
+		}
+		res = append(res, i)
+	}
+	return res
+}
+
+// ReadLog decodes the HAR file contents r without any of its entries, e.g. to
+// keep the creator and pages of an archive when selecting entries with the
+// index.
+func (ix *Index) ReadLog(r io.ReaderAt) (*HAR, error) {
+	var data []byte
+	if len(ix.Entries) == 0 {
+		data = make([]byte, ix.Size)
+		if _, err := r.ReadAt(data, 0); err != nil {
+			return nil, err
+		}
+	} else {
+		// the entries are contiguous, joined by commas
+		first, last := ix.Entries[0], ix.Entries[len(ix.Entries)-1]
+		end := last.Offset + last.Length
+		data = make([]byte, first.Offset+ix.Size-end)
+		if _, err := r.ReadAt(data[:first.Offset], 0); err != nil {
+			return nil, err
+		}
+		if _, err := r.ReadAt(data[first.Offset:], end); err != nil {
+			return nil, err
+		}
+	}
+	h := &HAR{}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// encode writes the HAR as JSON to w, identically to json.Marshal, calling
+// onEntry with the offset and length of each entry as it is written.
+func (h *HAR) encode(w io.Writer, onEntry func(off, n int64, e *Entry)) error {
+	// encode the log without entries and split it where they belong
+	lg := h.Log
+	lg.Entries = []Entry{}
+	head, err := json.Marshal(lg)
+	if err != nil {
+		return err
+	}
+	marker := []byte(`"entries":[`)
+	split := bytes.Index(head, marker) + len(marker)
+
+	cw := &countingWriter{w: w}
+	cw.Write([]byte(`{"log":`))
+	cw.Write(head[:split])
+	for i := range h.Log.Entries {
+		if i > 0 {
+			cw.Write([]byte{','})
+		}
+		data, err := json.Marshal(&h.Log.Entries[i])
+		if err != nil {
+			return err
+		}
+		off := cw.n
+		cw.Write(data)
+		if onEntry != nil {
+			onEntry(off, int64(len(data)), &h.Log.Entries[i])
+		}
+	}
+	cw.Write(head[split:])
+	cw.Write([]byte{'}'})
+	return cw.err
+}
+
+// countingWriter counts the bytes written to w, and remembers the first
+// error so that callers can check once at the end.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
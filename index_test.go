@@ -0,0 +1,104 @@
+package harhar
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, filename string)
+		want   error
+	}{
+		{
+			name:   "unchanged",
+			change: func(t *testing.T, filename string) {},
+		},
+		{
+			name: "appended",
+			change: func(t *testing.T, filename string) {
+				f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.WriteString("\n")
+				f.Close()
+			},
+			want: ErrStaleIndex,
+		},
+		{
+			name: "rewritten with the same size",
+			change: func(t *testing.T, filename string) {
+				data, err := os.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data = bytes.Replace(data, []byte("/one"), []byte("/two"), 1)
+				if err = os.WriteFile(filename, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				// in case the clock has not moved on since the index was written
+				later := time.Now().Add(time.Second)
+				if err = os.Chtimes(filename, later, later); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: ErrStaleIndex,
+		},
+		{
+			name: "index removed",
+			change: func(t *testing.T, filename string) {
+				if err := os.Remove(IndexFilename(filename)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: fs.ErrNotExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHAR("test")
+			for _, path := range []string{"/one", "/2"} {
+				var ent Entry
+				ent.Request.Method = "GET"
+				ent.Request.URL = "http://example.com" + path
+				ent.Response.StatusCode = 200
+				h.Log.Entries = append(h.Log.Entries, ent)
+			}
+			filename := filepath.Join(t.TempDir(), "test.har")
+			if _, err := h.WriteFileIndexed(filename); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(t, filename)
+			ix, err := ReadIndex(filename)
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+
+			f, err := os.Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			for i, want := range h.Log.Entries {
+				ent, err := ix.ReadEntry(f, i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ent.Request.URL != want.Request.URL {
+					t.Errorf("entry %d is %s, want %s", i, ent.Request.URL, want.Request.URL)
+				}
+			}
+		})
+	}
+}
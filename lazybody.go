@@ -0,0 +1,42 @@
+package harhar
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// lazyBody wraps a response body, keeping a copy of everything the caller
+// reads. done is called once with the data read when the body reaches EOF or
+// is closed, whichever comes first.
+type lazyBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func(data []byte, complete bool)
+
+	// expected body length, -1 if unknown
+	length int64
+}
+
+func (lb *lazyBody) Read(p []byte) (int, error) {
+	n, err := lb.ReadCloser.Read(p)
+	lb.buf.Write(p[:n])
+	if err == io.EOF {
+		lb.finish(true)
+	}
+	return n, err
+}
+
+func (lb *lazyBody) Close() error {
+	err := lb.ReadCloser.Close()
+	// callers often stop reading at the end of the data without seeing EOF
+	lb.finish(lb.length >= 0 && int64(lb.buf.Len()) == lb.length)
+	return err
+}
+
+func (lb *lazyBody) finish(complete bool) {
+	lb.once.Do(func() {
+		lb.done(lb.buf.Bytes(), complete)
+	})
+}
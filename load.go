@@ -0,0 +1,116 @@
+package harhar
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoadResult summarizes a load test, see ReplayRunner.Load.
+type LoadResult struct {
+	// Stats of the completed requests. Bytes counts response body bytes.
+	Stats
+
+	// Elapsed is the wall-clock duration of the test.
+	Elapsed time.Duration
+
+	// ByStatus counts responses by status code.
+	ByStatus map[int]int
+
+	// Errors counts failed requests by error message.
+	Errors map[string]int
+}
+
+// Load replays the recorded request mix with concurrency workers until
+// duration has elapsed (or ctx is cancelled, if duration is 0), cycling
+// through the entries in recorded order. Pacing (Speed) and Assert are not
+// applied. Requests are recorded by the Recorder as usual, so a Sampler or
+// Pause should be used to limit its memory use. If the Recorder's
+// RoundTripper is an *http.Transport, it is allowed to keep an idle
+// connection per worker, so that connections are reused between requests.
+func (r *ReplayRunner) Load(ctx context.Context, concurrency int, duration time.Duration) (*LoadResult, error) {
+	order := r.order()
+	if len(order) == 0 {
+		return nil, errors.New("harhar: no entries to replay")
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if t, ok := r.Recorder.RoundTripper.(*http.Transport); ok {
+		if t.MaxIdleConnsPerHost < concurrency {
+			t.MaxIdleConnsPerHost = concurrency
+		}
+		if t.MaxIdleConns != 0 && t.MaxIdleConns < concurrency {
+			t.MaxIdleConns = concurrency
+		}
+	}
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		next uint64
+	)
+	client := r.Recorder.Client(false)
+	res := &LoadResult{ByStatus: make(map[int]int), Errors: make(map[string]int)}
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := order[(atomic.AddUint64(&next, 1)-1)%uint64(len(order))]
+				t := time.Now()
+				status, n, err := r.fire(ctx, client, &r.HAR.Log.Entries[i])
+				if err != nil && ctx.Err() != nil {
+					// interrupted at the end of the test
+					return
+				}
+				ms := int(time.Since(t).Milliseconds())
+
+				mu.Lock()
+				if err != nil {
+					res.Errors[err.Error()]++
+				} else {
+					res.ByStatus[status]++
+					res.Count++
+					res.Bytes += n
+					res.Time += int64(ms)
+					if ms > res.Max {
+						res.Max = ms
+					}
+					res.times = append(res.times, ms)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+	res.finish()
+	return res, nil
+}
+
+// fire sends the request of a recorded entry and reads the whole response,
+// returning its status and body size.
+func (r *ReplayRunner) fire(ctx context.Context, client *http.Client, ent *Entry) (int, int64, error) {
+	req, err := r.newRequest(ctx, ent)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, n, err
+}
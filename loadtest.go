@@ -0,0 +1,119 @@
+package harhar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteK6 writes a k6 (https://k6.io) script to w which sends each request
+// in the archive in recorded order, sleeping for the recorded gaps between
+// them, and checks the response statuses.
+func (h *HAR) WriteK6(w io.Writer) error {
+	var sb strings.Builder
+	binary := false
+	entries := append([]Entry(nil), h.Log.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartTime().Before(entries[j].StartTime())
+	})
+
+	for i := range entries {
+		ent := &entries[i]
+		if i > 0 {
+			gap := ent.StartTime().Sub(entries[i-1].StartTime()).Seconds()
+			if gap > 0 {
+				fmt.Fprintf(&sb, "  sleep(%.3f);\n", gap)
+			}
+		}
+
+		req, err := ent.Request.ToHTTPRequest(context.Background())
+		if err != nil {
+			return fmt.Errorf("harhar: entry %d: %w", i, err)
+		}
+		body := "null"
+		if req.Body != nil {
+			data, err := io.ReadAll(req.Body)
+			if err != nil {
+				return err
+			}
+			if ent.Request.Body.Encoding == "base64" {
+				binary = true
+				body = "encoding.b64decode(" + jsLiteral(ent.Request.Body.Content) + ")"
+			} else {
+				body = jsLiteral(string(data))
+			}
+		}
+		headers := make(map[string]string)
+		for _, p := range goTestHeaders(req.Header) {
+			if v, ok := headers[p.Name]; ok {
+				headers[p.Name] = v + ", " + p.Value
+			} else {
+				headers[p.Name] = p.Value
+			}
+		}
+
+		fmt.Fprintf(&sb, "  res = http.request(%s, %s, %s, { headers: %s, redirects: 0 });\n",
+			jsLiteral(req.Method), jsLiteral(req.URL.String()), body, jsLiteral(headers))
+		fmt.Fprintf(&sb, "  check(res, { %s: (r) => r.status === %d });\n",
+			jsLiteral(fmt.Sprintf("%s %s is %d", req.Method, req.URL.Path, ent.Response.StatusCode)), ent.Response.StatusCode)
+	}
+
+	fmt.Fprintln(w, "import http from 'k6/http';")
+	fmt.Fprintln(w, "import { check, sleep } from 'k6';")
+	if binary {
+		fmt.Fprintln(w, "import encoding from 'k6/encoding';")
+	}
+	fmt.Fprintln(w, "\nexport default function () {\n  let res;")
+	_, err := fmt.Fprint(w, sb.String(), "}\n")
+	return err
+}
+
+// vegetaTarget is a line of vegeta's JSON target format.
+type vegetaTarget struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   []byte              `json:"body,omitempty"`
+}
+
+// WriteVegeta writes the archive's requests to w as vegeta
+// (https://github.com/tsenart/vegeta) targets, for use with
+// "vegeta attack -format=json".
+func (h *HAR) WriteVegeta(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for i := range h.Log.Entries {
+		req, err := h.Log.Entries[i].Request.ToHTTPRequest(context.Background())
+		if err != nil {
+			return fmt.Errorf("harhar: entry %d: %w", i, err)
+		}
+		t := vegetaTarget{Method: req.Method, URL: req.URL.String()}
+		for _, p := range goTestHeaders(req.Header) {
+			if t.Header == nil {
+				t.Header = make(map[string][]string)
+			}
+			t.Header[p.Name] = append(t.Header[p.Name], p.Value)
+		}
+		if req.Body != nil {
+			if t.Body, err = io.ReadAll(req.Body); err != nil {
+				return err
+			}
+		}
+		if err = enc.Encode(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsLiteral returns v as a JavaScript literal.
+func jsLiteral(v interface{}) string {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
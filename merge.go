@@ -0,0 +1,192 @@
+package harhar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MergeOptions controls how archives are combined by MergeWithOptions.
+type MergeOptions struct {
+	// Sources names each input archive, in the same order. Merged entries are
+	// tagged with the name of their source archive (Entry.Source). Unnamed
+	// sources are tagged with their index.
+	Sources []string
+
+	// Dedupe drops entries which duplicate an entry from an earlier source.
+	// If false, duplicates are kept and only reported.
+	Dedupe bool
+
+	// Window is the maximum difference between start times for two entries
+	// to be considered duplicates. Defaults to 1 second.
+	Window time.Duration
+
+	// MaxSkew is the maximum clock offset between two sources which is
+	// considered when looking for clock skew. Defaults to 5 minutes.
+	MaxSkew time.Duration
+}
+
+// ConflictKind describes the type of a merge Conflict.
+type ConflictKind string
+
+const (
+	// ConflictDuplicate is reported when the same exchange (method, URL, and
+	// body hashes) was recorded by two sources at nearly the same time.
+	ConflictDuplicate ConflictKind = "duplicate"
+
+	// ConflictClockSkew is reported when the exchanges shared between two
+	// sources are consistently offset in time, suggesting their clocks
+	// disagree.
+	ConflictClockSkew ConflictKind = "clock-skew"
+)
+
+// Conflict describes a problem detected while merging archives.
+type Conflict struct {
+	Kind ConflictKind
+
+	// Sources involved in the conflict.
+	Sources [2]string
+
+	// Method and URL of the duplicated entry (ConflictDuplicate only)
+	Method, URL string
+
+	// Offset between the start times of the entries (ConflictDuplicate), or
+	// the median offset of shared entries between the sources
+	// (ConflictClockSkew).
+	Offset time.Duration
+}
+
+// Merge combines the entries and pages of several archives into a new
+// archive, with entries sorted by start time and repeated pages removed. See
+// MergeWithOptions to detect duplicate entries and clock skew.
+func Merge(hars ...*HAR) *HAR {
+	res, _ := MergeWithOptions(MergeOptions{}, hars...)
+	return res
+}
+
+// MergeWithOptions combines the entries and pages of several archives into a
+// new archive, detecting duplicate entries and clock-skewed sources. Entries
+// are sorted by start time, and pages which appear in several archives are
+// only kept once. The log metadata (creator, browser, version) is taken from
+// the first archive.
+func MergeWithOptions(opts MergeOptions, hars ...*HAR) (*HAR, []Conflict) {
+	if opts.Window <= 0 {
+		opts.Window = time.Second
+	}
+	if opts.MaxSkew <= 0 {
+		opts.MaxSkew = 5 * time.Minute
+	}
+
+	res := &HAR{}
+	if len(hars) > 0 {
+		res.Log = hars[0].Log
+		res.Log.Pages = nil
+		res.Log.Entries = nil
+	}
+
+	type seenEntry struct {
+		source int
+		start  time.Time
+	}
+	var conflicts []Conflict
+	seen := make(map[string][]seenEntry)
+	offsets := make(map[[2]int][]time.Duration)
+	seenPages := make(map[Page]bool)
+
+	for si, h := range hars {
+		source := sourceName(opts.Sources, si)
+
+		for _, pg := range h.Log.Pages {
+			if !seenPages[pg] {
+				seenPages[pg] = true
+				res.Log.Pages = append(res.Log.Pages, pg)
+			}
+		}
+		for _, ent := range h.Log.Entries {
+			key := entryHash(&ent)
+			start := ent.StartTime()
+
+			dupe := false
+			for _, prev := range seen[key] {
+				if prev.source == si {
+					continue
+				}
+				delta := start.Sub(prev.start)
+				if delta < 0 {
+					delta = -delta
+				}
+				if delta <= opts.Window {
+					dupe = true
+					conflicts = append(conflicts, Conflict{
+						Kind:    ConflictDuplicate,
+						Sources: [2]string{sourceName(opts.Sources, prev.source), source},
+						Method:  ent.Request.Method,
+						URL:     ent.Request.URL,
+						Offset:  start.Sub(prev.start),
+					})
+					break
+				}
+				if delta <= opts.MaxSkew {
+					pair := [2]int{prev.source, si}
+					offsets[pair] = append(offsets[pair], start.Sub(prev.start))
+				}
+			}
+			seen[key] = append(seen[key], seenEntry{source: si, start: start})
+
+			if dupe && opts.Dedupe {
+				continue
+			}
+			ent.Source = source
+			res.Log.Entries = append(res.Log.Entries, ent)
+		}
+	}
+
+	pairs := make([][2]int, 0, len(offsets))
+	for pair := range offsets {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] == pairs[j][0] {
+			return pairs[i][1] < pairs[j][1]
+		}
+		return pairs[i][0] < pairs[j][0]
+	})
+	for _, pair := range pairs {
+		ds := offsets[pair]
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		median := ds[len(ds)/2]
+		if median > opts.Window || median < -opts.Window {
+			conflicts = append(conflicts, Conflict{
+				Kind:    ConflictClockSkew,
+				Sources: [2]string{sourceName(opts.Sources, pair[0]), sourceName(opts.Sources, pair[1])},
+				Offset:  median,
+			})
+		}
+	}
+
+	res.SortEntries()
+	return res, conflicts
+}
+
+// sourceName returns the name of the i-th source, or its index if unnamed.
+func sourceName(names []string, i int) string {
+	if i < len(names) && names[i] != "" {
+		return names[i]
+	}
+	return strconv.Itoa(i)
+}
+
+// entryHash identifies an exchange by method, URL, and body contents.
+func entryHash(e *Entry) string {
+	hash := sha256.New()
+	hash.Write([]byte(e.Request.Method + " " + e.Request.URL + "\n"))
+	hash.Write([]byte(e.Request.Body.Text()))
+	for _, p := range e.Request.Body.Params {
+		hash.Write([]byte(p.Name + "=" + p.Value + "\n"))
+	}
+	hash.Write([]byte{0})
+	hash.Write([]byte(e.Response.Body.Text()))
+	return hex.EncodeToString(hash.Sum(nil))
+}
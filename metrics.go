@@ -0,0 +1,68 @@
+package harhar
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// upper bounds of the capture overhead histogram buckets
+var overheadBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Metrics returns an expvar.Map describing the recorder itself, which can be
+// published for monitoring in production:
+//
+//	expvar.Publish("harhar", rec.Metrics())
+//
+// It contains:
+//
+//	entries_recorded  total entries added to the log
+//	entries_current   entries currently held in memory
+//	estimated_size    approximate bytes of memory held (see EstimatedSize)
+//	bytes_captured    total header and body bytes recorded
+//	status            entries recorded by response status code
+//	overhead          histogram of time spent capturing each entry, by
+//	                  bucket upper bound ("le_1ms", ..., "le_inf")
+func (c *Recorder) Metrics() *expvar.Map {
+	c.metricsOnce.Do(func() {
+		c.metrics.Init()
+		c.metrics.Set("status", new(expvar.Map).Init())
+		c.metrics.Set("overhead", new(expvar.Map).Init())
+		c.metrics.Set("entries_current", expvar.Func(func() interface{} {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return len(c.HAR.Log.Entries)
+		}))
+		c.metrics.Set("estimated_size", expvar.Func(func() interface{} {
+			return c.EstimatedSize()
+		}))
+	})
+	return &c.metrics
+}
+
+// observe updates the recorder metrics for a newly added entry.
+func (c *Recorder) observe(ent *Entry, overhead time.Duration) {
+	m := c.Metrics()
+	m.Add("entries_recorded", 1)
+
+	if size := transferred(ent); size > 0 {
+		m.Add("bytes_captured", size)
+	}
+
+	m.Get("status").(*expvar.Map).Add(strconv.Itoa(ent.Response.StatusCode), 1)
+
+	bucket := "le_inf"
+	for _, b := range overheadBuckets {
+		if overhead <= b {
+			bucket = "le_" + b.String()
+			break
+		}
+	}
+	m.Get("overhead").(*expvar.Map).Add(bucket, 1)
+}
@@ -0,0 +1,56 @@
+package harhar
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// WriteNDJSON writes the archive's entries to w as newline-delimited JSON,
+// one entry per line, for jq pipelines and log ingestion. Pages and log
+// metadata are not included.
+func (h *HAR) WriteNDJSON(w io.Writer) error {
+	return writeNDJSON(w, h.Log.Entries)
+}
+
+func writeNDJSON(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// StreamNDJSON decodes newline-delimited JSON entries from r, calling fn for
+// each. Decoding stops at the first error returned by fn.
+func StreamNDJSON(r io.Reader, fn func(Entry) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var ent Entry
+		err := dec.Decode(&ent)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(ent); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadNDJSON reads newline-delimited JSON entries from r into a new archive
+// created by creatorName.
+func ReadNDJSON(r io.Reader, creatorName string) (*HAR, error) {
+	h := NewHAR(creatorName)
+	h.Log.Entries = []Entry{}
+	err := StreamNDJSON(r, func(ent Entry) error {
+		h.Log.Entries = append(h.Log.Entries, ent)
+		return nil
+	})
+	return h, err
+}
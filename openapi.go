@@ -0,0 +1,449 @@
+package harhar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SpecIssueKind categorizes a problem found by ValidateOpenAPI.
+type SpecIssueKind string
+
+const (
+	// IssueUndocumented means no path/method in the spec matches the request.
+	IssueUndocumented SpecIssueKind = "undocumented-endpoint"
+	// IssueStatus means the response status is not documented.
+	IssueStatus SpecIssueKind = "unexpected-status"
+	// IssueRequestSchema means the request body does not match its schema.
+	IssueRequestSchema SpecIssueKind = "request-schema"
+	// IssueResponseSchema means the response body does not match its schema.
+	IssueResponseSchema SpecIssueKind = "response-schema"
+)
+
+// SpecIssue describes one way in which a recorded entry does not conform to
+// an OpenAPI document.
+type SpecIssue struct {
+	// Entry is the index of the entry in the archive.
+	Entry  int
+	Method string
+	URL    string
+
+	Kind SpecIssueKind
+
+	// Operation is the matched "METHOD /path/{template}", if any.
+	Operation string
+
+	// Detail describes the problem, e.g. a schema violation.
+	Detail string
+}
+
+func (si SpecIssue) String() string {
+	op := si.Operation
+	if op == "" {
+		op = si.Method + " " + si.URL
+	}
+	return fmt.Sprintf("#%d %s: %s: %s", si.Entry, op, si.Kind, si.Detail)
+}
+
+// ErrYAMLSpec is returned by ValidateOpenAPI for documents which are not
+// JSON objects, such as YAML.
+var ErrYAMLSpec = errors.New("harhar: OpenAPI document is not JSON (YAML is not supported, convert it with e.g. yq -o=json)")
+
+// ValidateOpenAPI checks every entry of h against an OpenAPI 3 document
+// (JSON encoded), and reports undocumented endpoints, undocumented response
+// statuses, and JSON request/response bodies which violate their schemas.
+//
+// YAML documents are rejected with ErrYAMLSpec rather than parsed, as there
+// is no YAML decoder in the standard library; convert them to JSON first,
+// e.g. with "yq -o=json".
+//
+// Schema validation supports the commonly used subset of JSON Schema: type,
+// nullable, enum, required, properties, additionalProperties, items, min/max
+// lengths and values, allOf/anyOf/oneOf and local $refs.
+func ValidateOpenAPI(h *HAR, spec []byte) ([]SpecIssue, error) {
+	if trimmed := bytes.TrimSpace(bytes.TrimPrefix(spec, []byte("\xef\xbb\xbf"))); len(trimmed) > 0 && trimmed[0] != '{' {
+		return nil, ErrYAMLSpec
+	}
+	doc := &openAPIDoc{}
+	if err := json.Unmarshal(spec, &doc.raw); err != nil {
+		return nil, fmt.Errorf("harhar: parsing OpenAPI document (only JSON is supported): %w", err)
+	}
+	if _, ok := doc.raw["openapi"]; !ok {
+		return nil, errors.New("harhar: not an OpenAPI 3 document")
+	}
+	doc.init()
+
+	var issues []SpecIssue
+	for i, ent := range h.Log.Entries {
+		issue := func(kind SpecIssueKind, op, detail string) {
+			issues = append(issues, SpecIssue{
+				Entry: i, Method: ent.Request.Method, URL: ent.Request.URL,
+				Kind: kind, Operation: op, Detail: detail,
+			})
+		}
+
+		u, err := url.Parse(ent.Request.URL)
+		if err != nil {
+			issue(IssueUndocumented, "", err.Error())
+			continue
+		}
+		tmpl, op := doc.match(strings.ToLower(ent.Request.Method), u.Path)
+		if op == nil {
+			issue(IssueUndocumented, "", "no matching path and method in spec")
+			continue
+		}
+		opName := strings.ToUpper(ent.Request.Method) + " " + tmpl
+
+		// request body
+		if rb, ok := doc.resolve(op["requestBody"]).(map[string]interface{}); ok {
+			if schema := contentSchema(doc.resolve(rb["content"]), ent.Request.Body.MIMEType); schema != nil {
+				if err := doc.validateBody(ent.Request.Body.Text(), schema); err != nil {
+					issue(IssueRequestSchema, opName, err.Error())
+				}
+			}
+		}
+
+		// response status and body
+		responses, _ := doc.resolve(op["responses"]).(map[string]interface{})
+		resp := findResponse(responses, ent.Response.StatusCode)
+		if resp == nil {
+			issue(IssueStatus, opName, "status "+strconv.Itoa(ent.Response.StatusCode)+" is not documented")
+			continue
+		}
+		rm, _ := doc.resolve(resp).(map[string]interface{})
+		if schema := contentSchema(doc.resolve(rm["content"]), ent.Response.Body.MIMEType); schema != nil {
+			if err := doc.validateBody(ent.Response.Body.Text(), schema); err != nil {
+				issue(IssueResponseSchema, opName, err.Error())
+			}
+		}
+	}
+	return issues, nil
+}
+
+type openAPIDoc struct {
+	raw      map[string]interface{}
+	basePath string
+	paths    []openAPIPath
+}
+
+type openAPIPath struct {
+	template string
+	segments []string
+	item     map[string]interface{}
+}
+
+func (d *openAPIDoc) init() {
+	if servers, ok := d.raw["servers"].([]interface{}); ok && len(servers) > 0 {
+		if srv, ok := servers[0].(map[string]interface{}); ok {
+			if s, ok := srv["url"].(string); ok {
+				if u, err := url.Parse(s); err == nil {
+					d.basePath = strings.TrimSuffix(u.Path, "/")
+				}
+			}
+		}
+	}
+
+	paths, _ := d.raw["paths"].(map[string]interface{})
+	for tmpl, item := range paths {
+		im, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		d.paths = append(d.paths, openAPIPath{
+			template: tmpl,
+			segments: strings.Split(strings.Trim(tmpl, "/"), "/"),
+			item:     im,
+		})
+	}
+	// prefer literal segments over templated ones (/users/me before /users/{id})
+	sort.Slice(d.paths, func(i, j int) bool {
+		return strings.Count(d.paths[i].template, "{") < strings.Count(d.paths[j].template, "{") ||
+			(strings.Count(d.paths[i].template, "{") == strings.Count(d.paths[j].template, "{") &&
+				d.paths[i].template < d.paths[j].template)
+	})
+}
+
+// match finds the operation for method on path, and its path template.
+func (d *openAPIDoc) match(method, path string) (string, map[string]interface{}) {
+	if d.basePath != "" {
+		if !strings.HasPrefix(path, d.basePath) {
+			return "", nil
+		}
+		path = path[len(d.basePath):]
+	}
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for _, p := range d.paths {
+		if len(p.segments) != len(segs) {
+			continue
+		}
+		ok := true
+		for i, s := range p.segments {
+			if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+				if segs[i] == "" {
+					ok = false
+					break
+				}
+				continue
+			}
+			if s != segs[i] {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		op, _ := d.resolve(p.item[method]).(map[string]interface{})
+		if op == nil {
+			continue
+		}
+		return p.template, op
+	}
+	return "", nil
+}
+
+// resolve follows a local $ref ("#/components/...") if v is a reference.
+func (d *openAPIDoc) resolve(v interface{}) interface{} {
+	for depth := 0; depth < 32; depth++ {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+		var cur interface{} = d.raw
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			cm, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			cur = cm[part]
+		}
+		v = cur
+	}
+	return nil
+}
+
+// findResponse returns the documented response for status: exact match,
+// then range ("4XX"), then "default".
+func findResponse(responses map[string]interface{}, status int) interface{} {
+	code := strconv.Itoa(status)
+	if r, ok := responses[code]; ok {
+		return r
+	}
+	if r, ok := responses[code[:1]+"XX"]; ok {
+		return r
+	}
+	if r, ok := responses[code[:1]+"xx"]; ok {
+		return r
+	}
+	return responses["default"]
+}
+
+// contentSchema returns the JSON schema for mimeType from a content map, or
+// nil if the body is not JSON or has no schema.
+func contentSchema(content interface{}, mimeType string) interface{} {
+	cm, ok := content.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	mt, _, err := mime.ParseMediaType(mimeType)
+	if err != nil || !isJSONMediaType(mt) {
+		return nil
+	}
+	for _, key := range []string{mt, "application/json", "*/*"} {
+		if media, ok := cm[key].(map[string]interface{}); ok {
+			return media["schema"]
+		}
+	}
+	return nil
+}
+
+func isJSONMediaType(mt string) bool {
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func (d *openAPIDoc) validateBody(body string, schema interface{}) error {
+	if body == "" {
+		return nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	return d.validate(v, schema, "$")
+}
+
+// validate checks value v against a JSON schema, returning the first
+// violation found. path locates v within the document for error messages.
+func (d *openAPIDoc) validate(v interface{}, schema interface{}, path string) error {
+	s, ok := d.resolve(schema).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if v == nil {
+		if s["nullable"] == true || typeAllows(s["type"], "null") || s["type"] == nil {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", path)
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if err := d.validate(v, sub, path); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if alts, ok := s[key].([]interface{}); ok && len(alts) > 0 {
+			matched := 0
+			var first error
+			for _, sub := range alts {
+				if err := d.validate(v, sub, path); err == nil {
+					matched++
+				} else if first == nil {
+					first = err
+				}
+			}
+			if matched == 0 {
+				return fmt.Errorf("%s: does not match any %s schema (%v)", path, key, first)
+			}
+			if key == "oneOf" && matched > 1 {
+				return fmt.Errorf("%s: matches %d oneOf schemas", path, matched)
+			}
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if s["type"] != nil && !typeAllows(s["type"], "object") {
+			return fmt.Errorf("%s: expected %v, got object", path, s["type"])
+		}
+		if req, ok := s["required"].([]interface{}); ok {
+			for _, r := range req {
+				if name, ok := r.(string); ok {
+					if _, present := val[name]; !present {
+						return fmt.Errorf("%s: missing required property %q", path, name)
+					}
+				}
+			}
+		}
+		props, _ := s["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k]; ok {
+				if err := d.validate(val[k], ps, path+"."+k); err != nil {
+					return err
+				}
+				continue
+			}
+			switch ap := s["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					return fmt.Errorf("%s: unexpected property %q", path, k)
+				}
+			case map[string]interface{}:
+				if err := d.validate(val[k], ap, path+"."+k); err != nil {
+					return err
+				}
+			}
+		}
+
+	case []interface{}:
+		if s["type"] != nil && !typeAllows(s["type"], "array") {
+			return fmt.Errorf("%s: expected %v, got array", path, s["type"])
+		}
+		if n, ok := schemaNumber(s["minItems"]); ok && float64(len(val)) < n {
+			return fmt.Errorf("%s: fewer than %v items", path, n)
+		}
+		if n, ok := schemaNumber(s["maxItems"]); ok && float64(len(val)) > n {
+			return fmt.Errorf("%s: more than %v items", path, n)
+		}
+		for i, item := range val {
+			if err := d.validate(item, s["items"], path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+
+	case string:
+		if s["type"] != nil && !typeAllows(s["type"], "string") {
+			return fmt.Errorf("%s: expected %v, got string", path, s["type"])
+		}
+		if n, ok := schemaNumber(s["minLength"]); ok && float64(len([]rune(val))) < n {
+			return fmt.Errorf("%s: shorter than %v characters", path, n)
+		}
+		if n, ok := schemaNumber(s["maxLength"]); ok && float64(len([]rune(val))) > n {
+			return fmt.Errorf("%s: longer than %v characters", path, n)
+		}
+
+	case bool:
+		if s["type"] != nil && !typeAllows(s["type"], "boolean") {
+			return fmt.Errorf("%s: expected %v, got boolean", path, s["type"])
+		}
+
+	case json.Number:
+		f, _ := val.Float64()
+		isInt := f == math.Trunc(f) && !strings.ContainsAny(val.String(), ".eE")
+		if s["type"] != nil && !typeAllows(s["type"], "number") && !(isInt && typeAllows(s["type"], "integer")) {
+			return fmt.Errorf("%s: expected %v, got number %s", path, s["type"], val)
+		}
+		if n, ok := schemaNumber(s["minimum"]); ok && f < n {
+			return fmt.Errorf("%s: %s is less than minimum %v", path, val, n)
+		}
+		if n, ok := schemaNumber(s["maximum"]); ok && f > n {
+			return fmt.Errorf("%s: %s is greater than maximum %v", path, val, n)
+		}
+	}
+	return nil
+}
+
+// typeAllows checks a schema "type" (a string or list of strings) for name.
+func typeAllows(t interface{}, name string) bool {
+	switch tv := t.(type) {
+	case string:
+		return tv == name
+	case []interface{}:
+		for _, x := range tv {
+			if x == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
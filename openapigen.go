@@ -0,0 +1,356 @@
+package harhar
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateOpenAPI builds an OpenAPI 3 document describing the traffic in the
+// archive. Entries are grouped into operations by method and templated path
+// (numeric, UUID and hex path segments become parameters, see TemplatePath),
+// and query parameters and JSON request and response bodies are described by
+// schemas inferred from all of the samples. The result is a starting point
+// to be reviewed and edited, not an authoritative spec.
+func GenerateOpenAPI(h *HAR, title string) map[string]interface{} {
+	type operation struct {
+		method, path string
+		params       []string // path parameter names
+		entries      []*Entry
+	}
+	var ops []*operation
+	byKey := make(map[string]*operation)
+	var servers []string
+	seenServers := make(map[string]bool)
+
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if srv := u.Scheme + "://" + u.Host; !seenServers[srv] {
+			seenServers[srv] = true
+			servers = append(servers, srv)
+		}
+		path, params := openAPIPathTemplate(u.Path)
+		key := strings.ToLower(e.Request.Method) + " " + path
+		op, ok := byKey[key]
+		if !ok {
+			op = &operation{method: strings.ToLower(e.Request.Method), path: path, params: params}
+			byKey[key] = op
+			ops = append(ops, op)
+		}
+		op.entries = append(op.entries, e)
+	}
+
+	paths := make(map[string]interface{})
+	for _, op := range ops {
+		item, ok := paths[op.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[op.path] = item
+		}
+
+		var parameters []interface{}
+		for _, name := range op.params {
+			parameters = append(parameters, map[string]interface{}{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		parameters = append(parameters, inferQueryParams(op.entries)...)
+
+		o := map[string]interface{}{
+			"summary":   strings.ToUpper(op.method) + " " + op.path,
+			"responses": inferResponses(op.entries),
+		}
+		if len(parameters) > 0 {
+			o["parameters"] = parameters
+		}
+		if rb := inferRequestBody(op.entries); rb != nil {
+			o["requestBody"] = rb
+		}
+		item[op.method] = o
+	}
+
+	srvs := make([]interface{}, len(servers))
+	for i, s := range servers {
+		srvs[i] = map[string]interface{}{"url": s}
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": title, "version": "1.0.0"},
+		"servers": srvs,
+		"paths":   paths,
+	}
+}
+
+// WriteOpenAPI writes the document from GenerateOpenAPI to w as JSON.
+func (h *HAR) WriteOpenAPI(w io.Writer, title string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(GenerateOpenAPI(h, title))
+}
+
+// openAPIPathTemplate templates a URL path and names its parameters after
+// the preceding segment, e.g. "/users/42" becomes "/users/{usersId}".
+func openAPIPathTemplate(p string) (string, []string) {
+	segs := strings.Split(TemplatePath(p), "/")
+	var params []string
+	for i, s := range segs {
+		if s != "{id}" {
+			continue
+		}
+		name := "id"
+		if i > 0 && segs[i-1] != "" && !strings.HasPrefix(segs[i-1], "{") {
+			name = segs[i-1] + "Id"
+		}
+		for n := 2; containsString(params, name); n++ {
+			name = strings.TrimRight(name, "0123456789") + strconv.Itoa(n)
+		}
+		params = append(params, name)
+		segs[i] = "{" + name + "}"
+	}
+	return strings.Join(segs, "/"), params
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// inferQueryParams describes the query parameters seen in entries. Those
+// present in every entry are required.
+func inferQueryParams(entries []*Entry) []interface{} {
+	var names []string
+	counts := make(map[string]int)
+	shapes := make(map[string]*jsonShape)
+	for _, e := range entries {
+		seen := make(map[string]bool)
+		for _, q := range e.Request.QueryParams {
+			if _, ok := shapes[q.Name]; !ok {
+				names = append(names, q.Name)
+				shapes[q.Name] = &jsonShape{}
+			}
+			if !seen[q.Name] {
+				seen[q.Name] = true
+				counts[q.Name]++
+			}
+			shapes[q.Name].addString(q.Value)
+		}
+	}
+	var res []interface{}
+	for _, name := range names {
+		res = append(res, map[string]interface{}{
+			"name": name, "in": "query", "required": counts[name] == len(entries),
+			"schema": shapes[name].schema(),
+		})
+	}
+	return res
+}
+
+// inferRequestBody describes the request bodies of entries, or returns nil
+// if none had one.
+func inferRequestBody(entries []*Entry) map[string]interface{} {
+	content := make(map[string]interface{})
+	shapes := make(map[string]*jsonShape)
+	required := true
+	for _, e := range entries {
+		mt, _, _ := mime.ParseMediaType(e.Request.Body.MIMEType)
+		if e.Request.BodySize <= 0 || mt == "" {
+			required = false
+			continue
+		}
+		inferBody(content, shapes, mt, e.Request.Body.Text(), e.Request.Body.Params)
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"required": required, "content": content}
+}
+
+// inferResponses describes the responses of entries by status code.
+func inferResponses(entries []*Entry) map[string]interface{} {
+	res := make(map[string]interface{})
+	shapes := make(map[int]map[string]*jsonShape)
+	for _, e := range entries {
+		code := e.Response.StatusCode
+		if code == 0 {
+			continue
+		}
+		status := strconv.Itoa(code)
+		resp, ok := res[status].(map[string]interface{})
+		if !ok {
+			desc := http.StatusText(code)
+			if desc == "" {
+				desc = e.Response.StatusText
+			}
+			resp = map[string]interface{}{"description": desc}
+			res[status] = resp
+			shapes[code] = make(map[string]*jsonShape)
+		}
+		mt, _, _ := mime.ParseMediaType(e.Response.Body.MIMEType)
+		if e.Response.Body.Size == 0 || mt == "" {
+			continue
+		}
+		content, ok := resp["content"].(map[string]interface{})
+		if !ok {
+			content = make(map[string]interface{})
+			resp["content"] = content
+		}
+		inferBody(content, shapes[code], mt, e.Response.Body.Text(), nil)
+	}
+	if len(res) == 0 {
+		res["default"] = map[string]interface{}{"description": "no response recorded"}
+	}
+	return res
+}
+
+// inferBody adds a body sample of media type mt to content.
+func inferBody(content map[string]interface{}, shapes map[string]*jsonShape, mt, text string, params []PostNameValuePair) {
+	shape, ok := shapes[mt]
+	if !ok {
+		shape = &jsonShape{}
+		shapes[mt] = shape
+	}
+	switch {
+	case isJSONMediaType(mt):
+		var v interface{}
+		if json.Unmarshal([]byte(text), &v) != nil {
+			return
+		}
+		shape.add(v)
+	case len(params) > 0:
+		obj := make(map[string]interface{}, len(params))
+		for _, p := range params {
+			obj[p.Name] = p.Value
+		}
+		shape.add(obj)
+	default:
+		content[mt] = map[string]interface{}{}
+		return
+	}
+	content[mt] = map[string]interface{}{"schema": shape.schema()}
+}
+
+// jsonShape accumulates the structure of JSON values to infer a schema.
+type jsonShape struct {
+	types   map[string]bool
+	objects int
+	props   map[string]*jsonShape
+	counts  map[string]int
+	items   *jsonShape
+}
+
+func (s *jsonShape) addType(t string) {
+	if s.types == nil {
+		s.types = make(map[string]bool)
+	}
+	s.types[t] = true
+}
+
+func (s *jsonShape) add(v interface{}) {
+	switch x := v.(type) {
+	case nil:
+		s.addType("null")
+	case bool:
+		s.addType("boolean")
+	case float64:
+		if x == math.Trunc(x) {
+			s.addType("integer")
+		} else {
+			s.addType("number")
+		}
+	case string:
+		s.addType("string")
+	case []interface{}:
+		s.addType("array")
+		if s.items == nil {
+			s.items = &jsonShape{}
+		}
+		for _, item := range x {
+			s.items.add(item)
+		}
+	case map[string]interface{}:
+		s.addType("object")
+		s.objects++
+		if s.props == nil {
+			s.props = make(map[string]*jsonShape)
+			s.counts = make(map[string]int)
+		}
+		for k, pv := range x {
+			if s.props[k] == nil {
+				s.props[k] = &jsonShape{}
+			}
+			s.props[k].add(pv)
+			s.counts[k]++
+		}
+	}
+}
+
+// addString adds a query parameter value, which may hold a number.
+func (s *jsonShape) addString(v string) {
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		s.addType("integer")
+	} else if _, err := strconv.ParseFloat(v, 64); err == nil {
+		s.addType("number")
+	} else {
+		s.addType("string")
+	}
+}
+
+func (s *jsonShape) schema() map[string]interface{} {
+	res := make(map[string]interface{})
+	if s.types["integer"] && s.types["number"] {
+		delete(s.types, "integer")
+	}
+	var types []string
+	for t := range s.types {
+		if t != "null" {
+			types = append(types, t)
+		}
+	}
+	if s.types["null"] {
+		res["nullable"] = true
+	}
+	if len(types) != 1 {
+		// mixed (or only null) values can't be described by one type
+		return res
+	}
+	res["type"] = types[0]
+
+	switch types[0] {
+	case "array":
+		if s.items != nil && len(s.items.types) > 0 {
+			res["items"] = s.items.schema()
+		} else {
+			res["items"] = map[string]interface{}{}
+		}
+	case "object":
+		props := make(map[string]interface{}, len(s.props))
+		var required []string
+		for k, ps := range s.props {
+			props[k] = ps.schema()
+			if s.counts[k] == s.objects {
+				required = append(required, k)
+			}
+		}
+		res["properties"] = props
+		if len(required) > 0 {
+			sort.Strings(required)
+			res["required"] = required
+		}
+	}
+	return res
+}
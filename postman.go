@@ -0,0 +1,143 @@
+package harhar
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+)
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanCollection struct {
+	Info postmanInfo   `json:"info"`
+	Item []postmanItem `json:"item"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// postmanItem is either a folder (Item) or a request.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item,omitempty"`
+	Request *postmanRequest `json:"request,omitempty"`
+}
+
+type postmanRequest struct {
+	Method string           `json:"method"`
+	Header []postmanKeyVals `json:"header"`
+	Body   *postmanBody     `json:"body,omitempty"`
+	URL    postmanURL       `json:"url"`
+}
+
+type postmanKeyVals struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string           `json:"mode"`
+	Raw        string           `json:"raw,omitempty"`
+	URLEncoded []postmanKeyVals `json:"urlencoded,omitempty"`
+	FormData   []postmanKeyVals `json:"formdata,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string           `json:"raw"`
+	Protocol string           `json:"protocol,omitempty"`
+	Host     []string         `json:"host,omitempty"`
+	Port     string           `json:"port,omitempty"`
+	Path     []string         `json:"path,omitempty"`
+	Query    []postmanKeyVals `json:"query,omitempty"`
+}
+
+// WritePostman writes the archive's requests to w as a Postman Collection
+// (v2.1) called name, with a folder for each host containing a folder for
+// each endpoint path (see Entry.Endpoint). Responses are not included.
+func (h *HAR) WritePostman(w io.Writer, name string) error {
+	coll := postmanCollection{
+		Info: postmanInfo{Name: name, Schema: postmanSchema},
+		Item: []postmanItem{},
+	}
+
+	// folders in order of first appearance
+	hosts := make(map[string]int)
+	paths := make(map[[2]string]int)
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			continue
+		}
+		hi, ok := hosts[u.Host]
+		if !ok {
+			hi = len(coll.Item)
+			hosts[u.Host] = hi
+			coll.Item = append(coll.Item, postmanItem{Name: u.Host})
+		}
+		tmpl := TemplatePath(u.Path)
+		pi, ok := paths[[2]string{u.Host, tmpl}]
+		if !ok {
+			pi = len(coll.Item[hi].Item)
+			paths[[2]string{u.Host, tmpl}] = pi
+			coll.Item[hi].Item = append(coll.Item[hi].Item, postmanItem{Name: tmpl})
+		}
+		folder := &coll.Item[hi].Item[pi]
+		folder.Item = append(folder.Item, postmanItem{
+			Name:    e.Request.Method + " " + u.RequestURI(),
+			Request: makePostmanRequest(&e.Request, u),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(coll)
+}
+
+func makePostmanRequest(r *Request, u *url.URL) *postmanRequest {
+	pr := &postmanRequest{
+		Method: r.Method,
+		Header: []postmanKeyVals{},
+		URL: postmanURL{
+			Raw:      r.URL,
+			Protocol: u.Scheme,
+			Host:     strings.Split(u.Hostname(), "."),
+			Port:     u.Port(),
+			Path:     strings.Split(strings.TrimPrefix(u.Path, "/"), "/"),
+		},
+	}
+	for _, q := range r.QueryParams {
+		pr.URL.Query = append(pr.URL.Query, postmanKeyVals{Key: q.Name, Value: q.Value})
+	}
+	for _, h := range r.Headers {
+		if strings.HasPrefix(h.Name, ":") || strings.EqualFold(h.Name, "Content-Length") {
+			continue
+		}
+		pr.Header = append(pr.Header, postmanKeyVals{Key: h.Name, Value: h.Value})
+	}
+
+	switch {
+	case len(r.Body.Params) > 0 && strings.HasPrefix(r.Body.MIMEType, "multipart/"):
+		pr.Body = &postmanBody{Mode: "formdata"}
+		for _, p := range r.Body.Params {
+			kv := postmanKeyVals{Key: p.Name, Value: p.Value, Type: "text"}
+			if p.FileName != "" {
+				// file contents must be reselected in Postman
+				kv = postmanKeyVals{Key: p.Name, Value: p.FileName, Type: "file"}
+			}
+			pr.Body.FormData = append(pr.Body.FormData, kv)
+		}
+	case len(r.Body.Params) > 0:
+		pr.Body = &postmanBody{Mode: "urlencoded"}
+		for _, p := range r.Body.Params {
+			pr.Body.URLEncoded = append(pr.Body.URLEncoded, postmanKeyVals{Key: p.Name, Value: p.Value})
+		}
+	case r.Body.Text() != "" && r.Body.Encoding == "":
+		pr.Body = &postmanBody{Mode: "raw", Raw: r.Body.Text()}
+	}
+	return pr
+}
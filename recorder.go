@@ -4,26 +4,202 @@ package harhar
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
-	"encoding/json"
+	"encoding/base64"
+	"encoding/hex"
+	"expvar"
+	"fmt"
 	"io"
+	"log"
+	"mime"
 	"net"
 	"net/http"
 	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 // Client embeds an upstream RoundTripper and wraps its methods to perform transparent HAR
 // logging for every request and response
+//
+// A Recorder is safe for concurrent use. Every completed exchange is added
+// to HAR.Log.Entries exactly once, regardless of whether it was recorded by
+// RoundTrip or ServeHTTP. Entries are in the order in which the exchanges
+// complete (not the order in which they started), unless SortOutput is set,
+// in which case each is inserted in order of start time. Clear, Rotate and
+// FlushAt remove entries from the log. Readers should use Snapshot or
+// WriteFile rather than accessing HAR directly while requests are in flight;
+// both observe a consistent copy of the entries list.
 type Recorder struct {
-	mu           sync.Mutex
-	RoundTripper http.RoundTripper `json:"-"`
-	Handler      http.Handler      `json:"-"`
+	mu            sync.Mutex
+	paused        atomic.Bool
+	flushing      atomic.Bool
+	rotateMu      sync.Mutex
+	redactKeyOnce sync.Once
+	metricsOnce   sync.Once
+	metrics       expvar.Map
+	size          int64     // estimated, see EstimatedSize
+	seq           uint64    // of the last entry added
+	flushRetry    time.Time // no flushes before, after an error
+	flushBackoff  time.Duration
+	RoundTripper  http.RoundTripper `json:"-"`
+	Handler       http.Handler      `json:"-"`
 
 	DisableHTTP2 func(bool)
 
+	// MaxBodySize limits the number of bytes of each response body kept in
+	// the HAR by the server-side recorder. 0 means no limit.
+	MaxBodySize int
+
+	// Clock returns the current time for timestamps and timings. Defaults to
+	// time.Now.
+	Clock func() time.Time
+
+	// NewID returns a new unique identifier, e.g. for request correlation.
+	// Defaults to random 128-bit hex strings.
+	NewID func() string
+
+	// RequestIDHeader, if set, is a header (e.g. "X-Harhar-Id") carrying a
+	// unique ID for each request, recorded as Entry.RequestID so that the HAR
+	// can be joined with server logs. The client recorder adds one from NewID
+	// unless the request already has it, and the server recorder records
+	// the ID sent by the client.
+	RequestIDHeader string
+
+	// StripRequestIDHeader removes RequestIDHeader from the recorded request
+	// headers. The ID is still sent, and kept in Entry.RequestID.
+	StripRequestIDHeader bool
+
+	// Sampler decides whether each request is recorded, see SampleEvery and
+	// SampleProbability. Requests which are not sampled pass through without
+	// any buffering. If nil, all requests are recorded.
+	Sampler func(*http.Request) bool
+
+	// Keep decides whether each completed entry is added to the log, e.g.
+	// only errors and slow requests (see KeepSlowOrFailed). If nil, all
+	// entries are kept.
+	Keep func(*Entry) bool
+
+	// Transform, if set, may modify each entry kept by Keep before it is
+	// added to the log, e.g. to strip the bodies of some requests. It runs
+	// before the header filters, redaction and body policies below.
+	Transform func(*Entry)
+
+	// IncludeHeaders, if set, lists the only headers which are recorded.
+	// ExcludeHeaders lists headers which are never recorded. Both match
+	// names case-insensitively and accept glob patterns such as
+	// "X-Internal-*". Unlike RedactHeaders, filtered headers leave no trace
+	// in the HAR. Filtering out Cookie or Set-Cookie also drops the parsed
+	// cookies.
+	IncludeHeaders []string
+	ExcludeHeaders []string
+
+	// RedactHeaders lists header names whose values must not be stored in
+	// the HAR. How they are recorded depends on RedactMode. Listing Cookie
+	// or Set-Cookie also redacts the parsed cookie values.
+	RedactHeaders []string
+
+	// RedactMode controls how RedactHeaders are recorded. The default is to
+	// remove them.
+	RedactMode RedactMode
+
+	// RedactKey is the HMAC key used by RedactHash. If nil, a random key is
+	// generated for the lifetime of the Recorder.
+	RedactKey []byte
+
+	// SkipBodyTypes lists MIME types (glob patterns such as "image/*") whose
+	// bodies are not stored in the HAR. Body sizes are still recorded. See
+	// DefaultSkipBodyTypes for a list of common static asset types.
+	SkipBodyTypes []string
+
+	// OmitUploadContents records multipart file uploads by file name,
+	// content type, size and SHA-256 hash only, instead of copying the file
+	// contents into the HAR.
+	OmitUploadContents bool
+
+	// TimeZone, if set, is the location used for all timestamps in recorded
+	// entries (start times, cache timestamps and cookie expiry), e.g.
+	// time.UTC. By default start times are in local time.
+	TimeZone *time.Location
+
+	// SortOutput orders the headers, query parameters and cookies of each
+	// entry by name, and keeps entries ordered by start time instead of
+	// completion time, so that diffs between captures of identical traffic
+	// are meaningful. EntrySink still receives entries as they complete.
+	SortOutput bool
+
+	// LazyBodies captures response bodies as the caller reads them, instead
+	// of reading them fully before RoundTrip returns. The entry is added
+	// when the body reaches EOF or is closed, and is marked Partial if it
+	// was closed early. Bodies which are never closed are not recorded.
+	LazyBodies bool
+
+	// BodyDir, if set, is a directory where request and response bodies are
+	// stored in files named by their SHA-256 hash, instead of inline in the
+	// HAR. Entries reference the files by name (BodyType.FileRef), see
+	// HAR.LoadBodies.
+	BodyDir string
+
+	// CompressBodies keeps large bodies gzip-compressed in memory until the
+	// HAR is written, which greatly reduces memory use for text-heavy
+	// traffic. The output format is unchanged, but code reading entries
+	// directly must use BodyType.Text and BodyResponseType.Text instead of
+	// the Content fields.
+	CompressBodies bool
+
+	// TraceContext returns the trace and span IDs active in a request
+	// context, for linking entries to distributed traces. For example, with
+	// OpenTelemetry:
+	//
+	//	rec.TraceContext = func(ctx context.Context) (string, string) {
+	//		sc := trace.SpanContextFromContext(ctx)
+	//		return sc.TraceID().String(), sc.SpanID().String()
+	//	}
+	//
+	// If nil or no IDs are returned, a W3C traceparent request header is
+	// used instead.
+	TraceContext func(ctx context.Context) (traceID, spanID string)
+
+	// TraceEvent, if set, is called with the request context and each
+	// completed entry, e.g. to add the timing breakdown as a span event.
+	TraceEvent func(ctx context.Context, e *Entry)
+
+	// FlushAt is a high-water mark for EstimatedSize. When exceeded, the
+	// recorded entries are written to FlushSink and removed (see Rotate),
+	// preventing unbounded memory growth during heavy capture. Flushes run
+	// in the background, and are disabled if FlushAt or FlushSink is unset.
+	// After a failed flush, the next is delayed by up to a minute. Call
+	// Flush before exiting to write the remaining entries.
+	FlushAt   int64
+	FlushSink Sink
+
+	// EntrySink, if set, receives each entry as it is added to the log,
+	// e.g. an Exporter.
+	EntrySink EntrySink
+
+	// RecordErrors records requests which fail without a response (e.g. a
+	// refused connection) as entries with Entry.Error set. Requests whose
+	// context is cancelled are always recorded.
+	RecordErrors bool
+
+	// RecordChunks records the size and timing of each chunk of response
+	// bodies as they arrive (Response.Chunks), for debugging streaming APIs.
+	RecordChunks bool
+
+	// WriteIndex makes WriteFile also write a sidecar index for fast
+	// queries of large archives (see IndexFilename).
+	WriteIndex bool
+
 	HAR *HAR
 }
 
@@ -66,32 +242,242 @@ func NewRecorder() *Recorder {
 	}
 }
 
+// Client returns an http.Client which records all requests through c. If
+// followRedirects is false, the client returns redirect responses to the
+// caller instead of following them (http.ErrUseLastResponse), so each hop is
+// recorded as its own entry and made explicitly by the caller.
+func (c *Recorder) Client(followRedirects bool) *http.Client {
+	cli := &http.Client{Transport: c}
+	if !followRedirects {
+		cli.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return cli
+}
+
 // WriteLog writes the HAR log format to the filename given, then returns the
 // number of bytes.
 func (c *Recorder) WriteFile(filename string) (int, error) {
-	data, err := json.Marshal(c.HAR)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.WriteIndex {
+		return c.HAR.WriteFileIndexed(filename)
+	}
+	return c.HAR.WriteFile(filename)
+}
+
+// Snapshot returns a copy of the HAR recorded so far. Entries recorded after
+// Snapshot returns are not reflected in the copy.
+func (c *Recorder) Snapshot() *HAR {
+	h, _ := c.snapshot()
+	return h
+}
+
+// snapshot returns a copy of the HAR and the sequence number of the last
+// entry added, which may since have been removed.
+func (c *Recorder) snapshot() (*HAR, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h := *c.HAR
+	h.Log.Pages = append([]Page(nil), c.HAR.Log.Pages...)
+	h.Log.Entries = append([]Entry(nil), c.HAR.Log.Entries...)
+	return &h, c.seq
+}
+
+// Pause stops recording. Requests continue to pass through untouched until
+// Resume is called.
+func (c *Recorder) Pause() {
+	c.paused.Store(true)
+}
+
+// Resume restarts recording after a call to Pause.
+func (c *Recorder) Resume() {
+	c.paused.Store(false)
+}
+
+// Enabled returns true unless recording has been paused.
+func (c *Recorder) Enabled() bool {
+	return !c.paused.Load()
+}
+
+// Clear discards all recorded entries and pages.
+func (c *Recorder) Clear() {
+	c.mu.Lock()
+	c.HAR.Log.Pages = nil
+	c.HAR.Log.Entries = nil
+	c.size = 0
+	c.mu.Unlock()
+}
+
+// SetDeterministic replaces the Clock and NewID of the recorder with
+// deterministic versions: the clock starts at the Unix epoch and advances by
+// exactly one millisecond each time it is read, and IDs are sequential. It
+// also enables SortOutput. Two recordings of the same scripted scenario then
+// produce identical archives.
+func (c *Recorder) SetDeterministic() {
+	c.SortOutput = true
+	var mu sync.Mutex
+	var ticks, ids int64
+	c.Clock = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		ticks++
+		return time.UnixMilli(ticks).UTC()
+	}
+	c.NewID = func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		ids++
+		return fmt.Sprintf("%016x", ids)
+	}
+
+	c.mu.Lock()
+	c.HAR.Log.Version = time.Unix(0, 0).UTC().Format("20060102150405")
+	c.HAR.Log.Creator.Version = c.HAR.Log.Version
+	c.mu.Unlock()
+}
+
+func (c *Recorder) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
+	}
+	return time.Now()
+}
+
+// msSince returns the number of milliseconds since t according to c's clock.
+func (c *Recorder) msSince(t time.Time) int {
+	return int(c.now().Sub(t).Milliseconds())
+}
+
+func (c *Recorder) newID() string {
+	if c.NewID != nil {
+		return c.NewID()
+	}
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// insertSorted moves the last of entries back to its position by start time,
+// assuming the others are already sorted.
+func insertSorted(entries []Entry) {
+	i := len(entries) - 1
+	start := entries[i].StartTime()
+	j := i
+	for j > 0 && entries[j-1].StartTime().After(start) {
+		j--
+	}
+	if j < i {
+		ent := entries[i]
+		copy(entries[j+1:], entries[j:i])
+		entries[j] = ent
+	}
+}
+
+// addEntry adds a completed entry to the log, unless discarded by Keep.
+// This is the only place entries are added, so the order of the log is the
+// order of completion, or of start time with SortOutput. overhead is the time
+// already spent capturing the entry, for metrics.
+func (c *Recorder) addEntry(ent Entry, overhead time.Duration) {
+	if c.Keep != nil && !c.Keep(&ent) {
+		return
+	}
+	if c.Transform != nil {
+		c.Transform(&ent)
+	}
+	addStart := time.Now()
+	c.filterHeaders(&ent)
+	c.stripRequestID(&ent)
+	c.redactHeaders(&ent)
+	c.skipBodies(&ent)
+	c.externalizeBodies(&ent)
+	if c.CompressBodies {
+		compressBodies(&ent)
+	}
+	if c.TimeZone != nil {
+		setTimeZone(&ent, c.TimeZone)
+	}
+	if c.SortOutput {
+		sortEntryFields(&ent)
+	}
+
+	size := estimateSize(&ent)
+	c.mu.Lock()
+	c.seq++
+	ent.seq = c.seq
+	c.HAR.Log.Entries = append(c.HAR.Log.Entries, ent)
+	if c.SortOutput {
+		insertSorted(c.HAR.Log.Entries)
+	}
+	c.size += size
+	overLimit := c.FlushAt > 0 && c.size > c.FlushAt && !addStart.Before(c.flushRetry)
+	c.mu.Unlock()
+
+	if overLimit && c.FlushSink != nil && c.flushing.CompareAndSwap(false, true) {
+		go c.backgroundFlush()
+	}
+	c.observe(&ent, overhead+time.Since(addStart))
+
+	if c.EntrySink != nil {
+		if err := c.EntrySink.WriteEntry(&ent); err != nil {
+			log.Println("unable to write HAR entry to sink ", err)
+		}
+	}
+}
+
+// delays before retrying background flushes after an error
+const (
+	minFlushBackoff = time.Second
+	maxFlushBackoff = time.Minute
+)
+
+// backgroundFlush writes the entries to FlushSink once FlushAt is exceeded,
+// backing off after errors so that a failing sink isn't retried for every
+// entry.
+func (c *Recorder) backgroundFlush() {
+	defer c.flushing.Store(false)
+	err := c.Rotate(c.FlushSink)
 	if err != nil {
-		return 0, err
+		log.Println("unable to flush HAR ", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.flushBackoff = 0
+		c.flushRetry = time.Time{}
+		return
+	}
+	c.flushBackoff *= 2
+	if c.flushBackoff < minFlushBackoff {
+		c.flushBackoff = minFlushBackoff
+	} else if c.flushBackoff > maxFlushBackoff {
+		c.flushBackoff = maxFlushBackoff
 	}
-	return len(data), os.WriteFile(filename, data, 0644)
+	c.flushRetry = time.Now().Add(c.flushBackoff)
 }
 
 // RoundTrip implements http.RoundTripper
 func (c *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
-	// http.RoundTripper must be safe for concurrent use
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	if !c.shouldRecord(req) {
+		return c.RoundTripper.RoundTrip(req)
+	}
 
 	var err error
 	ent := Entry{}
-	ent.Request, err = makeRequest(req)
+	captureStart := time.Now()
+	req, ent.RequestID = c.injectRequestID(req)
+	ent.Request, err = makeRequest(req, c.OmitUploadContents)
 	if err != nil {
 		return nil, err
 	}
+	overhead := time.Since(captureStart)
 
 	// if we re-use a connection many trace hooks don't fire, so
 	// set a start time for everything
-	now := time.Now()
+	now := c.now()
 	dnsStart := now
 	tlsStart := now
 	connWaitStart := now
@@ -99,20 +485,25 @@ func (c *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
 	sendStart := now
 	waitStart := now
 	respStart := now
+	startTime := now
+
+	// Expect: 100-continue delays the body until the server responds, so
+	// that time is moved from Send into Wait below
+	var wroteHeaders, got100 time.Time
 
 	trace := &httptrace.ClientTrace{
 		GetConn: func(hostPort string) {
-			connWaitStart = time.Now()
+			connWaitStart = c.now()
 		},
 		GotConn: func(connInfo httptrace.GotConnInfo) {
-			ent.Timings.Blocked = int(time.Since(connWaitStart).Milliseconds())
+			ent.Timings.Blocked = c.msSince(connWaitStart)
 		},
 
 		DNSStart: func(dnsInfo httptrace.DNSStartInfo) {
-			dnsStart = time.Now()
+			dnsStart = c.now()
 		},
 		DNSDone: func(dnsInfo httptrace.DNSDoneInfo) {
-			ent.Timings.DNS = int(time.Since(dnsStart).Milliseconds())
+			ent.Timings.DNS = c.msSince(dnsStart)
 			if len(dnsInfo.Addrs) > 0 {
 				ent.ServerIP = dnsInfo.Addrs[0].String()
 			} else {
@@ -121,48 +512,165 @@ func (c *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
 		},
 
 		ConnectStart: func(network, addr string) {
-			connStart = time.Now()
+			connStart = c.now()
 		},
 		ConnectDone: func(network, addr string, err error) {
-			ent.Timings.Connect = int(time.Since(connStart).Milliseconds())
-			sendStart = time.Now()
+			ent.Timings.Connect = c.msSince(connStart)
+			sendStart = c.now()
 		},
 
 		TLSHandshakeStart: func() {
-			tlsStart = time.Now()
+			tlsStart = c.now()
 		},
 		TLSHandshakeDone: func(connState tls.ConnectionState, err error) {
-			ent.Timings.SSL = int(time.Since(tlsStart).Milliseconds())
+			ent.Timings.SSL = c.msSince(tlsStart)
+			if err == nil {
+				ent.SecurityDetails = makeSecurityDetails(&connState)
+			}
+		},
+
+		WroteHeaders: func() {
+			wroteHeaders = c.now()
+		},
+		Got100Continue: func() {
+			got100 = c.now()
+		},
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			info := InformationalResponse{
+				StatusCode: code,
+				Time:       c.msSince(startTime),
+			}
+			for name, vals := range header {
+				for _, val := range vals {
+					info.Headers = append(info.Headers, NameValuePair{Name: name, Value: val})
+				}
+			}
+			ent.EarlyHints = append(ent.EarlyHints, info)
+			return nil
 		},
 
 		WroteRequest: func(info httptrace.WroteRequestInfo) {
-			ent.Timings.Send = int(time.Since(sendStart).Milliseconds())
-			waitStart = time.Now()
+			ent.Timings.Send = c.msSince(sendStart)
+			waitStart = c.now()
 		},
 		GotFirstResponseByte: func() {
-			ent.Timings.Wait = int(time.Since(waitStart).Milliseconds())
-			respStart = time.Now()
+			ent.Timings.Wait = c.msSince(waitStart)
+			respStart = c.now()
 		},
 	}
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	ctx, cacheState := withCacheSlot(httptrace.WithClientTrace(req.Context(), trace))
+	req = req.WithContext(ctx)
 
-	startTime := time.Now()
+	startTime = c.now()
 	resp, err := c.RoundTripper.RoundTrip(req)
 	if err != nil {
+		if req.Context().Err() != nil || c.RecordErrors {
+			// record what we know about requests which failed before a response
+			ent.Start = startTime.Format(time.RFC3339Nano)
+			ent.Time = c.msSince(startTime)
+			ent.Response = Response{HeadersSize: -1, BodySize: -1, Body: BodyResponseType{MIMEType: "x-unknown"}}
+			ent.Cancelled = req.Context().Err() != nil
+			ent.Error = err.Error()
+			ent.Tags = TagsFromContext(req.Context())
+			c.correlate(req.Context(), req, &ent)
+			c.addEntry(ent, overhead)
+		}
 		return resp, err
 	}
 
-	ent.Response, err = makeResponse(resp)
-	ent.Timings.Receive = int(time.Since(respStart).Milliseconds())
-	ent.Time = int(time.Since(startTime).Milliseconds())
+	if len(ent.EarlyHints) > 0 {
+		// the first response byte belonged to an informational response,
+		// so the final response only started once RoundTrip returned
+		respStart = c.now()
+		ent.Timings.Wait = int(respStart.Sub(waitStart).Milliseconds())
+		if !got100.IsZero() && !wroteHeaders.IsZero() {
+			expectWait := int(got100.Sub(wroteHeaders).Milliseconds())
+			ent.Timings.Send -= expectWait
+			ent.Timings.Wait += expectWait
+		}
+	}
+
+	if rwc, ok := resp.Body.(io.ReadWriteCloser); ok && resp.StatusCode == http.StatusSwitchingProtocols {
+		// the body is now the connection, record it when closed
+		ent.Response = makeResponseHead(resp, respStart)
+		ent.Upgraded = true
+		ent.Start = startTime.Format(time.RFC3339Nano)
+		ent.Tags = TagsFromContext(req.Context())
+		c.correlate(req.Context(), req, &ent)
+		ws := &wsCapture{now: c.now}
+		if !isWebSocket(req) {
+			// other protocols are passed through without capture
+			ws.sent.broken, ws.received.broken = true, true
+		}
+		resp.Body = &wsBody{ReadWriteCloser: rwc, ws: ws, done: func(msgs []WebSocketMessage) {
+			ent.WebSocketMessages = msgs
+			ent.Timings.Receive = c.msSince(respStart)
+			ent.Time = c.msSince(startTime)
+			c.addEntry(ent, overhead)
+		}}
+		return resp, nil
+	}
+
+	var chunks *chunkRecorder
+	if c.RecordChunks {
+		chunks = &chunkRecorder{ReadCloser: resp.Body, start: c.now(), now: c.now}
+		resp.Body = chunks
+	}
+
+	if resp.ProtoMajor >= 2 {
+		// the request's own Proto doesn't reflect the protocol used
+		ent.Request.HTTPVersion = resp.Proto
+		ent.Request.HeadersSize = -1
+	}
+	ent.Cache = makeCache(req, resp, startTime)
+	if cacheState.set {
+		// described by a CachingTransport
+		ent.Cache = cacheState.state
+	}
+	if ent.SecurityDetails == nil {
+		// reused connections don't fire the handshake hooks
+		ent.SecurityDetails = makeSecurityDetails(resp.TLS)
+	}
 	ent.Start = startTime.Format(time.RFC3339Nano)
+	ent.Tags = TagsFromContext(req.Context())
+	c.correlate(req.Context(), req, &ent)
 
-	c.HAR.Log.Entries = append(c.HAR.Log.Entries, ent)
-	return resp, err
+	finish := func() {
+		if chunks != nil {
+			ent.Response.Chunks = chunks.chunks
+		}
+		ent.Timings.Receive = c.msSince(respStart)
+		ent.Time = c.msSince(startTime)
+		ent.Cancelled = req.Context().Err() != nil
+		c.addEntry(ent, overhead)
+	}
+
+	if c.LazyBodies {
+		ent.Response = makeResponseHead(resp, respStart)
+		resp.Body = &lazyBody{
+			ReadCloser: resp.Body,
+			length:     resp.ContentLength,
+			done: func(data []byte, complete bool) {
+				setResponseBody(&ent.Response, resp, data)
+				ent.Response.Partial = !complete
+				finish()
+			},
+		}
+		return resp, nil
+	}
+
+	ent.Response, err = makeResponse(resp, respStart)
+	finish()
+	if err != nil {
+		// RoundTrippers must not return both
+		return nil, err
+	}
+	return resp, nil
 }
 
-// convert an http.Request to a harhar.Request
-func makeRequest(hr *http.Request) (Request, error) {
+// convert an http.Request to a harhar.Request. If omitUploads is set, file
+// uploads are summarized instead of copied.
+func makeRequest(hr *http.Request, omitUploads bool) (Request, error) {
 	r := Request{
 		Method:      hr.Method,
 		URL:         hr.URL.String(),
@@ -171,10 +679,7 @@ func makeRequest(hr *http.Request) (Request, error) {
 		BodySize:    -1,
 	}
 
-	h2 := hr.Header.Clone()
-	buf := &bytes.Buffer{}
-	h2.Write(buf)
-	r.HeadersSize = buf.Len() + 4 // incl. CRLF CRLF
+	r.HeadersSize = requestHeadersSize(hr)
 
 	// parse out headers
 	r.Headers = make([]NameValuePair, 0, len(hr.Header))
@@ -184,29 +689,15 @@ func makeRequest(hr *http.Request) (Request, error) {
 		}
 	}
 
+	r.FetchMetadata = makeFetchMetadata(hr.Header)
+
 	// parse out cookies
 	r.Cookies = make([]Cookie, 0, len(hr.Cookies()))
 	for _, c := range hr.Cookies() {
-		nc := Cookie{
-			Name:     c.Name,
-			Path:     c.Path,
-			Value:    c.Value,
-			Domain:   c.Domain,
-			Expires:  c.Expires.Format(time.RFC3339Nano),
-			HTTPOnly: c.HttpOnly,
-			Secure:   c.Secure,
-		}
-		r.Cookies = append(r.Cookies, nc)
+		r.Cookies = append(r.Cookies, makeCookie(c, time.Time{}))
 	}
 
-	// parse query params
-	qp := hr.URL.Query()
-	r.QueryParams = make([]NameValuePair, 0, len(qp))
-	for name, vals := range qp {
-		for _, val := range vals {
-			r.QueryParams = append(r.QueryParams, NameValuePair{Name: name, Value: val})
-		}
-	}
+	r.QueryParams = parseQuery(hr.URL.RawQuery)
 
 	if hr.Body == nil {
 		r.BodySize = 0
@@ -221,6 +712,7 @@ func makeRequest(hr *http.Request) (Request, error) {
 	hr.Body.Close()
 	bodbuf := bytes.NewReader(bodyData)
 	hr.Body = io.NopCloser(bodbuf)
+	r.Trailers = makeTrailers(hr.Trailer)
 
 	r.BodySize = len(bodyData)
 	r.Body.MIMEType = hr.Header.Get("Content-Type")
@@ -228,7 +720,9 @@ func makeRequest(hr *http.Request) (Request, error) {
 		// default per RFC2616
 		r.Body.MIMEType = "application/octet-stream"
 	}
-	switch r.Body.MIMEType {
+	// multipart types always carry a boundary parameter
+	mediaType, _, _ := mime.ParseMediaType(r.Body.MIMEType)
+	switch mediaType {
 	case "form-data", "multipart/form-data":
 		err = hr.ParseMultipartForm(32 << 20) // 32 MB
 		if err != nil {
@@ -241,18 +735,27 @@ func makeRequest(hr *http.Request) (Request, error) {
 				if err != nil {
 					return r, err
 				}
-				fileContents, err := io.ReadAll(fhandle)
+				p := PostNameValuePair{
+					Name:        key,
+					FileName:    fh.Filename,
+					ContentType: fh.Header.Get("Content-Type"),
+				}
+				if omitUploads {
+					hash := sha256.New()
+					_, err = io.Copy(hash, fhandle)
+					p.Size = fh.Size
+					p.SHA256 = hex.EncodeToString(hash.Sum(nil))
+				} else {
+					var fileContents []byte
+					fileContents, err = io.ReadAll(fhandle)
+					p.Value = string(fileContents)
+				}
 				fhandle.Close()
 				if err != nil {
 					return r, err
 				}
 
-				r.Body.Params = append(r.Body.Params, PostNameValuePair{
-					Name:        key,
-					Value:       string(fileContents),
-					FileName:    fh.Filename,
-					ContentType: fh.Header.Get("Content-Type"),
-				})
+				r.Body.Params = append(r.Body.Params, p)
 			}
 		}
 		for key, vals := range hr.MultipartForm.Value {
@@ -280,26 +783,87 @@ func makeRequest(hr *http.Request) (Request, error) {
 		}
 
 	default:
+		if !utf8.Valid(bodyData) {
+			// JSON strings can't hold arbitrary bytes
+			r.Body.Content = base64.StdEncoding.EncodeToString(bodyData)
+			r.Body.Encoding = "base64"
+			break
+		}
 		r.Body.Content = string(bodyData)
+		r.GraphQL = makeGraphQL(r.Body.MIMEType, r.Body.Content)
 	}
 
 	return r, nil
 }
 
-// convert an http.Response to a harhar.Response
-func makeResponse(hr *http.Response) (Response, error) {
+// parseQuery splits a raw query string into name/value pairs in their
+// original order, including repeated names. Values which cannot be decoded
+// are kept as sent. Request.URL always holds the exact query string.
+func parseQuery(rawQuery string) []NameValuePair {
+	res := []NameValuePair{}
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		name, val, _ := strings.Cut(part, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if v, err := url.QueryUnescape(val); err == nil {
+			val = v
+		}
+		res = append(res, NameValuePair{Name: name, Value: val})
+	}
+	return res
+}
+
+// convert an http.Response received at now to a harhar.Response
+func makeResponse(hr *http.Response, now time.Time) (Response, error) {
+	r := makeResponseHead(hr, now)
+
+	// read in all the data and replace the ReadCloser
+	bodyData, err := io.ReadAll(hr.Body)
+	hr.Body.Close()
+	setResponseBody(&r, hr, bodyData)
+	if err != nil {
+		// keep what was received, the caller still sees the error
+		r.Partial = true
+		hr.Body = io.NopCloser(io.MultiReader(bytes.NewReader(bodyData), errReader{err}))
+		return r, err
+	}
+	hr.Body = io.NopCloser(bytes.NewReader(bodyData))
+	return r, nil
+}
+
+// errReader always fails with err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// statusText returns the reason phrase sent by the server, which may differ
+// from the standard text, falling back to the standard text if there was none.
+func statusText(hr *http.Response) string {
+	code, phrase, _ := strings.Cut(hr.Status, " ")
+	if phrase != "" && code == strconv.Itoa(hr.StatusCode) {
+		return phrase
+	}
+	return http.StatusText(hr.StatusCode)
+}
+
+// convert everything but the body of an http.Response received at now to a
+// harhar.Response
+func makeResponseHead(hr *http.Response, now time.Time) Response {
 	r := Response{
 		StatusCode:  hr.StatusCode,
-		StatusText:  http.StatusText(hr.StatusCode),
+		StatusText:  statusText(hr),
 		HTTPVersion: hr.Proto,
 		HeadersSize: -1,
 		BodySize:    -1,
 	}
 
-	h2 := hr.Header.Clone()
-	buf := &bytes.Buffer{}
-	h2.Write(buf)
-	r.HeadersSize = buf.Len() + 4 // incl. CRLF CRLF
+	r.HeadersSize = responseHeadersSize(hr)
 
 	// parse out headers
 	r.Headers = make([]NameValuePair, 0, len(hr.Header))
@@ -308,24 +872,22 @@ func makeResponse(hr *http.Response) (Response, error) {
 			r.Headers = append(r.Headers, NameValuePair{Name: name, Value: val})
 		}
 	}
-	rurl, err := hr.Location()
-	if err == nil {
-		r.RedirectURL = rurl.String()
+	if hr.StatusCode >= 300 && hr.StatusCode <= 399 {
+		r.RedirectURL = hr.Header.Get("Location")
+		// resolved relative to hr.Request, if known
+		if rurl, err := hr.Location(); err == nil {
+			r.RedirectTarget = rurl.String()
+		}
 	}
 
-	// parse out cookies
+	// parse out cookies, Max-Age is relative to the response Date
+	date, err := http.ParseTime(hr.Header.Get("Date"))
+	if err != nil {
+		date = now
+	}
 	r.Cookies = make([]Cookie, 0, len(hr.Cookies()))
 	for _, c := range hr.Cookies() {
-		nc := Cookie{
-			Name:     c.Name,
-			Path:     c.Path,
-			Value:    c.Value,
-			Domain:   c.Domain,
-			Expires:  c.Expires.Format(time.RFC3339Nano),
-			HTTPOnly: c.HttpOnly,
-			Secure:   c.Secure,
-		}
-		r.Cookies = append(r.Cookies, nc)
+		r.Cookies = append(r.Cookies, makeCookie(c, date))
 	}
 
 	// FIXME: net/http transparently decompresses content,
@@ -335,23 +897,99 @@ func makeResponse(hr *http.Response) (Response, error) {
 	//
 	// see hr.Uncompressed for next steps
 
-	// read in all the data and replace the ReadCloser
-	bodyData, err := io.ReadAll(hr.Body)
-	if err != nil {
-		return r, err
+	r.Body.MIMEType = hr.Header.Get("Content-Type")
+	if r.Body.MIMEType == "" {
+		// default per RFC2616
+		r.Body.MIMEType = "application/octet-stream"
 	}
-	hr.Body.Close()
-	hr.Body = io.NopCloser(bytes.NewReader(bodyData))
+
+	return r
+}
+
+// setResponseBody fills in the body of r once all of bodyData has been read
+// from hr. Trailers are only available at this point.
+func setResponseBody(r *Response, hr *http.Response, bodyData []byte) {
+	r.Trailers = makeTrailers(hr.Trailer)
 	r.Body.Content = string(bodyData)
 	r.Body.Compression = 0
 	r.Body.Size = len(bodyData)
 	r.BodySize = r.Body.Size
+}
 
-	r.Body.MIMEType = hr.Header.Get("Content-Type")
-	if r.Body.MIMEType == "" {
-		// default per RFC2616
-		r.Body.MIMEType = "application/octet-stream"
+// requestHeadersSize returns the size of the request line and headers of an
+// HTTP/1.x request, up to and including the blank line. HTTP/2 and later
+// compress headers, so there is no meaningful size and -1 is returned.
+func requestHeadersSize(hr *http.Request) int {
+	if hr.ProtoMajor >= 2 {
+		return -1
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s %s\r\n", hr.Method, hr.URL.RequestURI(), hr.Proto)
+	if hr.Host != "" && hr.Header.Get("Host") == "" {
+		// the Host header is held separately
+		fmt.Fprintf(buf, "Host: %s\r\n", hr.Host)
 	}
+	hr.Header.Write(buf)
+	return buf.Len() + 2 // CRLF
+}
 
-	return r, nil
+// responseHeadersSize returns the size of the status line and headers of an
+// HTTP/1.x response, up to and including the blank line, or -1 for HTTP/2
+// and later.
+func responseHeadersSize(hr *http.Response) int {
+	if hr.ProtoMajor >= 2 {
+		return -1
+	}
+	status := hr.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", hr.StatusCode, http.StatusText(hr.StatusCode))
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s\r\n", hr.Proto, status)
+	hr.Header.Write(buf)
+	return buf.Len() + 2 // CRLF
+}
+
+// convert an http.Cookie to a harhar.Cookie. A Max-Age attribute overrides
+// Expires, and is converted to an expiry relative to now.
+func makeCookie(c *http.Cookie, now time.Time) Cookie {
+	nc := Cookie{
+		Name:     c.Name,
+		Path:     c.Path,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		HTTPOnly: c.HttpOnly,
+		Secure:   c.Secure,
+	}
+	expires := c.Expires
+	if c.MaxAge > 0 {
+		expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	} else if c.MaxAge < 0 {
+		// Max-Age=0 deletes the cookie immediately
+		expires = time.Unix(0, 0).UTC()
+	}
+	if !expires.IsZero() {
+		nc.Expires = expires.Format(time.RFC3339Nano)
+	}
+	switch c.SameSite {
+	case http.SameSiteStrictMode:
+		nc.SameSite = "Strict"
+	case http.SameSiteLaxMode:
+		nc.SameSite = "Lax"
+	case http.SameSiteNoneMode:
+		nc.SameSite = "None"
+	}
+	return nc
+}
+
+// convert trailers to a list of name/value pairs, omitting those which were
+// announced but never sent.
+func makeTrailers(trailer http.Header) []NameValuePair {
+	var res []NameValuePair
+	for name, vals := range trailer {
+		for _, val := range vals {
+			res = append(res, NameValuePair{Name: name, Value: val})
+		}
+	}
+	return res
 }
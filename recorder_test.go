@@ -0,0 +1,426 @@
+package harhar
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestRecorderConcurrent records from RoundTrip and ServeHTTP while reading
+// the log with Snapshot, AutoSave and WriteFile, and should be run with -race.
+func TestRecorderConcurrent(t *testing.T) {
+	for _, sorted := range []bool{false, true} {
+		t.Run(fmt.Sprint("SortOutput=", sorted), func(t *testing.T) {
+			testRecorderConcurrent(t, sorted)
+		})
+	}
+}
+
+func testRecorderConcurrent(t *testing.T, sorted bool) {
+	const workers, perWorker = 8, 50
+
+	rec := NewRecorder()
+	rec.SortOutput = sorted
+	rec.RoundTripper = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("ok")),
+			Request:    req,
+		}, nil
+	})
+	rec.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+
+	var snapMu sync.Mutex
+	var snapshots [][]string
+	keep := func(h *HAR) {
+		snapMu.Lock()
+		snapshots = append(snapshots, entryURLs(h))
+		snapMu.Unlock()
+	}
+	stop := rec.AutoSave(SinkFunc(func(h *HAR) error {
+		keep(h)
+		return nil
+	}), time.Millisecond, func(err error) { t.Error(err) })
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				keep(rec.Snapshot())
+			}
+		}
+	}()
+	go func() {
+		defer readers.Done()
+		filename := filepath.Join(t.TempDir(), "out.har")
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := rec.WriteFile(filename); err != nil {
+				t.Error(err)
+				return
+			}
+			h, err := ReadFile(filename)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			keep(h)
+		}
+	}()
+
+	var writers sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			cli := rec.Client(true)
+			for i := 0; i < perWorker; i++ {
+				url := fmt.Sprintf("http://example.com/%d/%d", w, i)
+				if w%2 == 0 {
+					resp, err := cli.Get(url)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				} else {
+					rec.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+				}
+			}
+		}(w)
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	final := entryURLs(rec.Snapshot())
+	if len(final) != workers*perWorker {
+		t.Fatalf("got %d entries, want %d", len(final), workers*perWorker)
+	}
+	seen := make(map[string]bool)
+	next := make([]int, workers)
+	for _, u := range final {
+		if seen[u] {
+			t.Fatalf("entry %s recorded twice", u)
+		}
+		seen[u] = true
+
+		// each worker's requests complete (and start) in sequence
+		var w, i int
+		fmt.Sscanf(u, "http://example.com/%d/%d", &w, &i)
+		if i != next[w] {
+			t.Fatalf("entry %s out of order, want request %d of worker %d first", u, next[w], w)
+		}
+		next[w]++
+	}
+
+	if sorted {
+		entries := rec.Snapshot().Log.Entries
+		for i := 1; i < len(entries); i++ {
+			if entries[i].StartTime().Before(entries[i-1].StartTime()) {
+				t.Fatalf("entry %d started before entry %d", i, i-1)
+			}
+		}
+		// readers may see later entries inserted before earlier ones
+		return
+	}
+	for _, snap := range snapshots {
+		for i, u := range snap {
+			if final[i] != u {
+				t.Fatalf("snapshot of %d entries is not a prefix of the log", len(snap))
+			}
+		}
+	}
+}
+
+// TestRecorderOrder records a slow exchange which starts first and a fast one
+// which completes first, from every combination of RoundTrip and ServeHTTP.
+func TestRecorderOrder(t *testing.T) {
+	tests := []struct {
+		slow, fast string
+		sorted     bool
+		want       []string
+	}{
+		{"RoundTrip", "RoundTrip", false, []string{"/fast", "/slow"}},
+		{"RoundTrip", "ServeHTTP", false, []string{"/fast", "/slow"}},
+		{"ServeHTTP", "RoundTrip", false, []string{"/fast", "/slow"}},
+		{"ServeHTTP", "ServeHTTP", false, []string{"/fast", "/slow"}},
+		{"RoundTrip", "RoundTrip", true, []string{"/slow", "/fast"}},
+		{"RoundTrip", "ServeHTTP", true, []string{"/slow", "/fast"}},
+		{"ServeHTTP", "RoundTrip", true, []string{"/slow", "/fast"}},
+		{"ServeHTTP", "ServeHTTP", true, []string{"/slow", "/fast"}},
+	}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s,%s,SortOutput=%v", tt.slow, tt.fast, tt.sorted)
+		t.Run(name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			block := func(r *http.Request) {
+				if r.URL.Path == "/slow" {
+					close(started)
+					<-release
+				}
+			}
+
+			rec := NewRecorder()
+			rec.SortOutput = tt.sorted
+			var ticks int64
+			rec.Clock = func() time.Time {
+				return time.Unix(0, 0).Add(time.Duration(atomic.AddInt64(&ticks, 1)) * time.Millisecond)
+			}
+			rec.RoundTripper = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				block(req)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Proto:      "HTTP/1.1",
+					ProtoMajor: 1,
+					ProtoMinor: 1,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("ok")),
+					Request:    req,
+				}, nil
+			})
+			rec.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				block(r)
+				io.WriteString(w, "ok")
+			})
+			do := func(kind, path string) {
+				url := "http://example.com" + path
+				if kind == "ServeHTTP" {
+					rec.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+					return
+				}
+				resp, err := rec.Client(false).Get(url)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				do(tt.slow, "/slow")
+			}()
+			<-started
+			do(tt.fast, "/fast")
+			close(release)
+			<-done
+
+			h, last := rec.snapshot()
+			var got []string
+			for _, ent := range h.Log.Entries {
+				got = append(got, strings.TrimPrefix(ent.Request.URL, "http://example.com"))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("entries are %v, want %v", got, tt.want)
+			}
+			// numbered in the order they completed
+			if last != 2 {
+				t.Fatalf("last entry is %d, want 2", last)
+			}
+			wantSeq := map[string]uint64{"http://example.com/fast": 1, "http://example.com/slow": 2}
+			for _, ent := range h.Log.Entries {
+				if ent.seq != wantSeq[ent.Request.URL] {
+					t.Errorf("entry %s is number %d, want %d", ent.Request.URL, ent.seq, wantSeq[ent.Request.URL])
+				}
+			}
+		})
+	}
+}
+
+// TestRecorderSeq records from RoundTrip and ServeHTTP while entries are
+// removed by Rotate and FlushAt and read by Snapshot, and checks that every
+// entry is numbered once, in the order they are added, and written once.
+func TestRecorderSeq(t *testing.T) {
+	for _, sorted := range []bool{false, true} {
+		t.Run(fmt.Sprint("SortOutput=", sorted), func(t *testing.T) {
+			testRecorderSeq(t, sorted)
+		})
+	}
+}
+
+func testRecorderSeq(t *testing.T, sorted bool) {
+	const workers, perWorker = 4, 50
+
+	rec := NewRecorder()
+	rec.SortOutput = sorted
+	rec.RoundTripper = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("ok")),
+			Request:    req,
+		}, nil
+	})
+	rec.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+
+	// segments written by Rotate and FlushAt, in order
+	var segments [][]uint64
+	sink := SinkFunc(func(h *HAR) error {
+		var seqs []uint64
+		for _, ent := range h.Log.Entries {
+			seqs = append(seqs, ent.seq)
+		}
+		segments = append(segments, seqs) // writes are serialized by Rotate
+		return nil
+	})
+	rec.FlushAt = 2000
+	rec.FlushSink = sink
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		var prev uint64
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			h, last := rec.snapshot()
+			if last < prev {
+				t.Errorf("snapshot went back from entry %d to %d", prev, last)
+				return
+			}
+			prev = last
+			for i, ent := range h.Log.Entries {
+				if ent.seq == 0 || ent.seq > last {
+					t.Errorf("snapshot up to entry %d has entry %d", last, ent.seq)
+					return
+				}
+				if !sorted && i > 0 && ent.seq <= h.Log.Entries[i-1].seq {
+					t.Errorf("entry %d listed after entry %d", ent.seq, h.Log.Entries[i-1].seq)
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			if err := rec.Rotate(sink); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	var writers sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			cli := rec.Client(true)
+			for i := 0; i < perWorker; i++ {
+				url := fmt.Sprintf("http://example.com/%d/%d", w, i)
+				if w%2 == 0 {
+					resp, err := cli.Get(url)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				} else {
+					rec.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+				}
+			}
+		}(w)
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Snapshot().Log.Entries); n != 0 {
+		t.Fatalf("%d entries left after Flush", n)
+	}
+
+	var prev uint64
+	for _, seg := range segments {
+		for i, seq := range seg {
+			if !sorted && i > 0 && seq <= seg[i-1] {
+				t.Fatalf("entry %d written after entry %d", seq, seg[i-1])
+			}
+			if seq <= prev {
+				t.Fatalf("entry %d written in a segment after entry %d", seq, prev)
+			}
+		}
+		for _, seq := range seg {
+			if seq > prev {
+				prev = seq
+			}
+		}
+	}
+	seen := make(map[uint64]bool)
+	for _, seg := range segments {
+		for _, seq := range seg {
+			if seen[seq] {
+				t.Fatalf("entry %d written twice", seq)
+			}
+			seen[seq] = true
+		}
+	}
+	for seq := uint64(1); seq <= workers*perWorker; seq++ {
+		if !seen[seq] {
+			t.Fatalf("entry %d never written", seq)
+		}
+	}
+}
+
+func entryURLs(h *HAR) []string {
+	urls := make([]string, len(h.Log.Entries))
+	for i, ent := range h.Log.Entries {
+		urls[i] = ent.Request.URL
+	}
+	return urls
+}
@@ -0,0 +1,84 @@
+package harhar
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// RedactMode controls how the values of Recorder.RedactHeaders are recorded.
+type RedactMode int
+
+const (
+	// RedactRemove drops redacted headers from the entry entirely.
+	RedactRemove RedactMode = iota
+
+	// RedactMask replaces redacted header values with "[REDACTED]".
+	RedactMask
+
+	// RedactHash replaces redacted header values with an HMAC-SHA256 token
+	// keyed by Recorder.RedactKey, so equal secrets map to equal tokens
+	// across the archive without the secret itself being stored.
+	RedactHash
+)
+
+// redactHeaders applies the recorder's header redaction to an entry.
+func (c *Recorder) redactHeaders(ent *Entry) {
+	if len(c.RedactHeaders) == 0 {
+		return
+	}
+	names := make(map[string]bool, len(c.RedactHeaders))
+	for _, name := range c.RedactHeaders {
+		names[http.CanonicalHeaderKey(name)] = true
+	}
+
+	ent.Request.Headers = c.redactPairs(ent.Request.Headers, names)
+	ent.Response.Headers = c.redactPairs(ent.Response.Headers, names)
+	if names["Cookie"] {
+		ent.Request.Cookies = c.redactCookies(ent.Request.Cookies)
+	}
+	if names["Set-Cookie"] {
+		ent.Response.Cookies = c.redactCookies(ent.Response.Cookies)
+	}
+}
+
+func (c *Recorder) redactPairs(pairs []NameValuePair, names map[string]bool) []NameValuePair {
+	kept := pairs[:0]
+	for _, p := range pairs {
+		if names[http.CanonicalHeaderKey(p.Name)] {
+			if c.RedactMode == RedactRemove {
+				continue
+			}
+			p.Value = c.redactValue(p.Value)
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+func (c *Recorder) redactCookies(cookies []Cookie) []Cookie {
+	if c.RedactMode == RedactRemove {
+		return []Cookie{}
+	}
+	for i := range cookies {
+		cookies[i].Value = c.redactValue(cookies[i].Value)
+	}
+	return cookies
+}
+
+func (c *Recorder) redactValue(v string) string {
+	if c.RedactMode != RedactHash {
+		return "[REDACTED]"
+	}
+	c.redactKeyOnce.Do(func() {
+		if c.RedactKey == nil {
+			c.RedactKey = make([]byte, 32)
+			rand.Read(c.RedactKey)
+		}
+	})
+	mac := hmac.New(sha256.New, c.RedactKey)
+	mac.Write([]byte(v))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,159 @@
+package harhar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrNotRecorded is returned by a Replayer when no entry in the archive
+// matches a request and no Fallback is configured.
+var ErrNotRecorded = errors.New("harhar: no recorded entry matches request")
+
+// maximum number of recorded redirect hops followed by a Replayer
+const maxReplayRedirects = 10
+
+// Replayer is an http.RoundTripper that serves responses from a recorded HAR
+// instead of contacting the network. Requests are matched to entries by method
+// and URL, and each entry is served once in recorded order before matches are
+// reused.
+type Replayer struct {
+	mu    sync.Mutex
+	used  []bool
+	index map[string][]int // method+URL => entry indexes
+
+	// HAR to serve responses from. The entries are indexed on the first
+	// request, so neither HAR nor Tags may be modified after that.
+	HAR *HAR
+
+	// Fallback handles requests which have no recorded entry. If nil,
+	// ErrNotRecorded is returned instead.
+	Fallback http.RoundTripper
+
+	// FollowRedirects makes the Replayer resolve recorded redirect chains
+	// itself and return only the final response. By default every recorded
+	// hop is reproduced faithfully, leaving the client to decide whether to
+	// follow it.
+	FollowRedirects bool
+
+	// Tags, if set, restricts replay to entries with at least one of the
+	// tags (see WithTags).
+	Tags []string
+
+	// ReproduceChunks makes response bodies with recorded chunks
+	// (Response.Chunks) arrive in the same pieces and with the same pacing
+	// as they were recorded.
+	ReproduceChunks bool
+}
+
+// NewReplayer returns a new Replayer serving responses from h.
+func NewReplayer(h *HAR) *Replayer {
+	return &Replayer{HAR: h}
+}
+
+// NewReplayerFromDir returns a new Replayer serving responses from all of the
+// HAR files (*.har and *.har.gz) in dir, so that test suites can share a
+// library of recorded traffic. Files are loaded in name order, which is the
+// order matching entries are served in.
+func NewReplayerFromDir(dir string) (*Replayer, error) {
+	var names []string
+	for _, pattern := range []string{"*.har", "*.har.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, matches...)
+	}
+	sort.Strings(names)
+
+	h := NewHAR("harhar-replay")
+	for _, name := range names {
+		part, err := ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("harhar: loading %s: %w", name, err)
+		}
+		h.Log.Pages = append(h.Log.Pages, part.Log.Pages...)
+		h.Log.Entries = append(h.Log.Entries, part.Log.Entries...)
+	}
+	return NewReplayer(h), nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	ent := r.find(req.Method, req.URL.String())
+	if ent == nil {
+		if r.Fallback != nil {
+			return r.Fallback.RoundTrip(req)
+		}
+		return nil, ErrNotRecorded
+	}
+
+	for hops := 0; r.FollowRedirects && hops < maxReplayRedirects; hops++ {
+		if ent.Response.StatusCode < 300 || ent.Response.StatusCode > 399 || ent.Response.RedirectURL == "" {
+			break
+		}
+		method := ent.Request.Method
+		if ent.Response.StatusCode != http.StatusTemporaryRedirect && ent.Response.StatusCode != http.StatusPermanentRedirect {
+			method = http.MethodGet
+		}
+		target := ent.Response.RedirectTarget
+		if target == "" {
+			target = ent.Response.RedirectURL
+		}
+		next := r.find(method, target)
+		if next == nil {
+			break
+		}
+		ent = next
+	}
+
+	resp, err := ent.Response.ToHTTPResponse()
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	if !r.ReproduceChunks || len(ent.Response.Chunks) == 0 {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &chunkReplayer{data: body, chunks: ent.Response.Chunks}
+	return resp, nil
+}
+
+// find returns the first unused entry matching method and URL, or the last
+// matching entry if all of them have been used.
+func (r *Replayer) find(method, u string) *Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.index == nil {
+		r.used = make([]bool, len(r.HAR.Log.Entries))
+		r.index = make(map[string][]int)
+		for i, ent := range r.HAR.Log.Entries {
+			if !ent.hasAnyTag(r.Tags) {
+				continue
+			}
+			key := ent.Request.Method + " " + ent.Request.URL
+			r.index[key] = append(r.index[key], i)
+		}
+	}
+
+	matches := r.index[method+" "+u]
+	for _, i := range matches {
+		if !r.used[i] {
+			r.used[i] = true
+			return &r.HAR.Log.Entries[i]
+		}
+	}
+	if len(matches) > 0 {
+		return &r.HAR.Log.Entries[matches[len(matches)-1]]
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package harhar
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// reportEntry is an entry with its position in the waterfall, as
+// percentages of the archive's total duration.
+type reportEntry struct {
+	*Entry
+	Index         int
+	Offset, Width float64
+	Segments      []reportSegment
+}
+
+// reportSegment is one timing phase, its width a percentage of the entry's.
+type reportSegment struct {
+	Phase string
+	Width float64
+	MS    int
+}
+
+// WriteHTML writes a standalone HTML report of the archive to w, with a
+// waterfall chart of the entries and expandable request and response
+// details. The page has no external dependencies.
+func (h *HAR) WriteHTML(w io.Writer, title string) error {
+	var first, last time.Time
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		start := e.StartTime()
+		end := start.Add(time.Duration(e.Time) * time.Millisecond)
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+	total := float64(last.Sub(first).Milliseconds())
+	if total <= 0 {
+		total = 1
+	}
+
+	entries := make([]reportEntry, len(h.Log.Entries))
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		re := reportEntry{
+			Entry:  e,
+			Index:  i,
+			Offset: 100 * float64(e.StartTime().Sub(first).Milliseconds()) / total,
+			Width:  100 * float64(e.Time) / total,
+		}
+		t := e.Timings
+		phases := []reportSegment{
+			{"blocked", 0, t.Blocked}, {"dns", 0, t.DNS}, {"connect", 0, t.Connect},
+			{"ssl", 0, t.SSL}, {"send", 0, t.Send}, {"wait", 0, t.Wait}, {"receive", 0, t.Receive},
+		}
+		sum := 0
+		for _, seg := range phases {
+			if seg.MS > 0 {
+				sum += seg.MS
+			}
+		}
+		for _, seg := range phases {
+			if seg.MS > 0 {
+				seg.Width = 100 * float64(seg.MS) / float64(sum)
+				re.Segments = append(re.Segments, seg)
+			}
+		}
+		entries[i] = re
+	}
+
+	return reportTemplate.Execute(w, map[string]interface{}{
+		"Title":    title,
+		"Entries":  entries,
+		"Duration": int(last.Sub(first).Milliseconds()),
+	})
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font: 13px sans-serif; margin: 1em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { padding: 2px 6px; border-bottom: 1px solid #eee; text-align: left; vertical-align: top; }
+td.url { max-width: 40em; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+td.wf { width: 40%; position: relative; }
+.bar { position: absolute; top: 4px; height: 10px; min-width: 1px; display: flex; background: #ddd; }
+.bar span { height: 10px; }
+.blocked { background: #ccc; } .dns { background: #1b9e77; } .connect { background: #d95f02; }
+.ssl { background: #7570b3; } .send { background: #e7298a; } .wait { background: #66a61e; }
+.receive { background: #377eb8; }
+.err { color: #c00; }
+details pre { white-space: pre-wrap; word-break: break-all; max-height: 30em; overflow: auto; background: #f8f8f8; padding: 4px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Entries}} requests over {{.Duration}}ms.
+<span class="blocked">&nbsp;&nbsp;</span> blocked
+<span class="dns">&nbsp;&nbsp;</span> dns
+<span class="connect">&nbsp;&nbsp;</span> connect
+<span class="ssl">&nbsp;&nbsp;</span> ssl
+<span class="send">&nbsp;&nbsp;</span> send
+<span class="wait">&nbsp;&nbsp;</span> wait
+<span class="receive">&nbsp;&nbsp;</span> receive</p>
+<table>
+<tr><th>#</th><th>Method</th><th>URL</th><th>Status</th><th>Type</th><th>Size</th><th>Time</th><th>Waterfall</th></tr>
+{{range .Entries}}
+<tr>
+<td>{{.Index}}</td>
+<td>{{.Request.Method}}</td>
+<td class="url" title="{{.Request.URL}}">{{.Request.URL}}</td>
+<td{{if ge .Response.StatusCode 400}} class="err"{{end}}>{{.Response.StatusCode}}</td>
+<td>{{.Response.Body.MIMEType}}</td>
+<td>{{.Response.BodySize}}</td>
+<td>{{.Time}}ms</td>
+<td class="wf"><div class="bar" style="left: {{printf "%.2f" .Offset}}%; width: {{printf "%.2f" .Width}}%">{{range .Segments}}<span class="{{.Phase}}" style="width: {{printf "%.2f" .Width}}%" title="{{.Phase}} {{.MS}}ms"></span>{{end}}</div></td>
+</tr>
+<tr><td></td><td colspan="7"><details><summary>details</summary>
+<b>Request headers</b>
+<pre>{{.Request.Method}} {{.Request.URL}} {{.Request.HTTPVersion}}
+{{range .Request.Headers}}{{.Name}}: {{.Value}}
+{{end}}</pre>
+{{with .Request.Body.Text}}<b>Request body</b><pre>{{.}}</pre>{{end}}
+{{range .Request.Body.Params}}<pre>{{.Name}}={{.Value}}</pre>{{end}}
+<b>Response headers</b>
+<pre>{{.Response.HTTPVersion}} {{.Response.StatusCode}} {{.Response.StatusText}}
+{{range .Response.Headers}}{{.Name}}: {{.Value}}
+{{end}}</pre>
+{{with .Response.Body.Text}}<b>Response body</b><pre>{{.}}</pre>{{end}}
+</details></td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
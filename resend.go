@@ -0,0 +1,179 @@
+package harhar
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// ReplayRunner re-sends the requests recorded in a HAR to a live server, in
+// recorded order, and records the new exchanges. Unlike Replayer, which serves
+// recorded responses without contacting the network, it is intended for
+// verifying a server against captured traffic.
+type ReplayRunner struct {
+	// HAR to send requests from.
+	HAR *HAR
+
+	// Target, if set, replaces the scheme and host of every request, e.g.
+	// to send production captures to a staging server. The recorded Host
+	// header is replaced too.
+	Target *url.URL
+
+	// Recorder records the replayed exchanges. Redirects are not followed,
+	// since each recorded hop is replayed as its own request.
+	Recorder *Recorder
+
+	// Tags, if set, restricts replay to entries with at least one of the
+	// tags (see WithTags).
+	Tags []string
+
+	// Speed paces the replay to reproduce the recorded gaps between request
+	// start times, scaled by Speed: 1 is the original timing, 2 is twice as
+	// fast. 0 sends each request as soon as the previous one completes.
+	Speed float64
+
+	// Assert, if set, compares each replayed response to the recorded one
+	// and reports differences in ReplayResult.Mismatches.
+	Assert *AssertOptions
+}
+
+// ReplayResult describes one replayed request.
+type ReplayResult struct {
+	// Index of the recorded entry in the source HAR.
+	Index int
+
+	// Recorded entry, and the Replayed exchange. Replayed is nil if the
+	// request could not be sent or was not recorded.
+	Recorded *Entry
+	Replayed *Entry
+
+	// Err is the error sending the request, if any.
+	Err error
+
+	// Mismatches between the recorded and replayed responses, see
+	// ReplayRunner.Assert.
+	Mismatches []Mismatch
+}
+
+// NewReplayRunner returns a ReplayRunner for h which records into a new
+// Recorder.
+func NewReplayRunner(h *HAR) *ReplayRunner {
+	return &ReplayRunner{HAR: h, Recorder: NewRecorder()}
+}
+
+// Run sends each request in turn, returning a result for each. Failed
+// requests are reported in their result rather than stopping the run; only
+// cancellation of ctx ends it early, returning the results so far and the
+// context's error.
+func (r *ReplayRunner) Run(ctx context.Context) ([]ReplayResult, error) {
+	client := r.Recorder.Client(false)
+	var results []ReplayResult
+	var first time.Time
+	start := time.Now()
+	for _, i := range r.order() {
+		if r.Speed > 0 {
+			offset := r.HAR.Log.Entries[i].StartTime()
+			if first.IsZero() {
+				first = offset
+			}
+			due := start.Add(time.Duration(float64(offset.Sub(first)) / r.Speed))
+			if err := sleepUntil(ctx, due); err != nil {
+				return results, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		res := ReplayResult{Index: i, Recorded: &r.HAR.Log.Entries[i]}
+		res.Replayed, res.Err = r.send(ctx, client, res.Recorded)
+		if r.Assert != nil && res.Replayed != nil {
+			res.Mismatches = CompareResponses(res.Recorded, res.Replayed, *r.Assert)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// sleepUntil waits until t, or returns early with the error of ctx if it is
+// cancelled first.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// order returns the indexes of the entries to replay, by start time.
+func (r *ReplayRunner) order() []int {
+	var idx []int
+	for i := range r.HAR.Log.Entries {
+		if r.HAR.Log.Entries[i].hasAnyTag(r.Tags) {
+			idx = append(idx, i)
+		}
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return r.HAR.Log.Entries[idx[a]].StartTime().Before(r.HAR.Log.Entries[idx[b]].StartTime())
+	})
+	return idx
+}
+
+// newRequest rebuilds the request of a recorded entry, redirected to Target.
+func (r *ReplayRunner) newRequest(ctx context.Context, ent *Entry) (*http.Request, error) {
+	req, err := ent.Request.ToHTTPRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// let the transport negotiate compression, so that responses are
+	// decompressed before they are recorded and compared
+	req.Header.Del("Accept-Encoding")
+	if r.Target != nil {
+		req.URL.Scheme = r.Target.Scheme
+		req.URL.Host = r.Target.Host
+		req.Host = ""
+	}
+	return req, nil
+}
+
+// send replays a single recorded entry using client, and returns the new
+// entry recorded for it.
+func (r *ReplayRunner) send(ctx context.Context, client *http.Client, ent *Entry) (*Entry, error) {
+	req, err := r.newRequest(ctx, ent)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Recorder.mu.Lock()
+	before := len(r.Recorder.HAR.Log.Entries)
+	r.Recorder.mu.Unlock()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Recorder.mu.Lock()
+	defer r.Recorder.mu.Unlock()
+	entries := r.Recorder.HAR.Log.Entries
+	if len(entries) <= before {
+		// filtered out by Recorder.Keep
+		return nil, nil
+	}
+	replayed := entries[len(entries)-1]
+	return &replayed, nil
+}
@@ -0,0 +1,235 @@
+package harhar
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Rotate writes the entries recorded so far to s as one segment and removes
+// them from the recorder, so the next segment starts empty. The entries stay
+// in the log until the segment has been written, and are kept if it cannot
+// be. Calls to Rotate wait for any other in progress to finish.
+func (c *Recorder) Rotate(s Sink) error {
+	c.rotateMu.Lock()
+	defer c.rotateMu.Unlock()
+
+	seg, last := c.snapshot()
+	if len(seg.Log.Entries) == 0 {
+		return nil
+	}
+	if err := s.Write(seg); err != nil {
+		return err
+	}
+
+	// remove what was written, keeping anything recorded meanwhile
+	written := make(map[string]bool, len(seg.Log.Pages))
+	for _, pg := range seg.Log.Pages {
+		written[pg.ID] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var entries []Entry
+	for i := range c.HAR.Log.Entries {
+		if ent := &c.HAR.Log.Entries[i]; ent.seq > last {
+			entries = append(entries, *ent)
+		} else {
+			c.size -= estimateSize(ent)
+		}
+	}
+	var pages []Page
+	for _, pg := range c.HAR.Log.Pages {
+		if !written[pg.ID] {
+			pages = append(pages, pg)
+		}
+	}
+	c.HAR.Log.Entries = entries
+	c.HAR.Log.Pages = pages
+	return nil
+}
+
+// Flush writes the entries recorded so far to FlushSink (see Rotate), after
+// waiting for any background flush in progress to finish. Call it before
+// exiting so that no entries are lost. It does nothing if FlushSink is unset.
+func (c *Recorder) Flush() error {
+	if c.FlushSink == nil {
+		return nil
+	}
+	return c.Rotate(c.FlushSink)
+}
+
+// AutoRotate calls Rotate every interval. Errors are passed to onError,
+// which may be nil. The returned stop function ends rotation and writes the
+// final segment.
+func (c *Recorder) AutoRotate(s Sink, every time.Duration, onError func(error)) (stop func() error) {
+	return periodically(every, onError, func() error {
+		return c.Rotate(s)
+	})
+}
+
+// SegmentHook post-processes a finished segment file, and returns the name of
+// the resulting file (if any) for the next hook.
+type SegmentHook func(filename string) (string, error)
+
+// SegmentSink is a Sink which writes each archive to a new numbered file,
+// then runs post-processing hooks (compression, upload, deletion) on it with
+// a pool of background workers. Only the segment currently being written
+// is left unprocessed on disk.
+type SegmentSink struct {
+	// Pattern for segment file names, containing one verb for the sequence
+	// number, e.g. "capture-%05d.har".
+	Pattern string
+
+	// PostProcess hooks are run in order on each finished segment.
+	PostProcess []SegmentHook
+
+	// Workers is the number of segments post-processed concurrently,
+	// default 1.
+	Workers int
+
+	// OnError is called with any post-processing errors, if not nil.
+	OnError func(error)
+
+	once  sync.Once
+	mu    sync.Mutex
+	seq   int
+	queue chan string
+	wg    sync.WaitGroup
+}
+
+// Write implements Sink.
+func (s *SegmentSink) Write(h *HAR) error {
+	s.once.Do(s.start)
+
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf(s.Pattern, s.seq)
+	s.mu.Unlock()
+
+	if _, err := h.WriteFile(name); err != nil {
+		return err
+	}
+	if len(s.PostProcess) > 0 {
+		s.queue <- name
+	}
+	return nil
+}
+
+// Close waits for all queued post-processing to finish. The SegmentSink
+// must not be used after Close.
+func (s *SegmentSink) Close() error {
+	s.once.Do(s.start)
+	close(s.queue)
+	s.wg.Wait()
+	return nil
+}
+
+func (s *SegmentSink) start() {
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	s.queue = make(chan string, workers)
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for name := range s.queue {
+				s.process(name)
+			}
+		}()
+	}
+}
+
+func (s *SegmentSink) process(name string) {
+	for _, hook := range s.PostProcess {
+		next, err := hook(name)
+		if err != nil {
+			if s.OnError != nil {
+				s.OnError(fmt.Errorf("harhar: post-processing %s: %w", name, err))
+			}
+			return
+		}
+		if next == "" {
+			return
+		}
+		name = next
+	}
+}
+
+// GzipSegment is a SegmentHook which compresses the segment to a .gz file
+// and removes the original.
+func GzipSegment(filename string) (string, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	gzname := filename + ".gz"
+	out, err := os.Create(gzname)
+	if err != nil {
+		return "", err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(filename)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(gzname)
+		return "", err
+	}
+	in.Close()
+	return gzname, os.Remove(filename)
+}
+
+// UploadSegment returns a SegmentHook which PUTs the segment to baseURL plus
+// the segment's base file name, e.g. a bucket or collector URL ending in "/".
+func UploadSegment(baseURL string, client *http.Client) SegmentHook {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(filename string) (string, error) {
+		f, err := os.Open(filename)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		st, err := f.Stat()
+		if err != nil {
+			return "", err
+		}
+
+		req, err := http.NewRequest(http.MethodPut, baseURL+filepath.Base(filename), f)
+		if err != nil {
+			return "", err
+		}
+		req.ContentLength = st.Size()
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return "", fmt.Errorf("upload failed: %s", resp.Status)
+		}
+		return filename, nil
+	}
+}
+
+// DeleteSegment is a SegmentHook which removes the segment file, e.g. after
+// it has been uploaded.
+func DeleteSegment(filename string) (string, error) {
+	return "", os.Remove(filename)
+}
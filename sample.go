@@ -0,0 +1,49 @@
+package harhar
+
+import (
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// SampleEvery returns a Recorder.Sampler which records 1 in every n requests.
+func SampleEvery(n int) func(*http.Request) bool {
+	var count uint64
+	return func(*http.Request) bool {
+		return n <= 1 || atomic.AddUint64(&count, 1)%uint64(n) == 1
+	}
+}
+
+// SampleProbability returns a Recorder.Sampler which records each request
+// with probability p (between 0 and 1).
+func SampleProbability(p float64) func(*http.Request) bool {
+	return func(*http.Request) bool {
+		return rand.Float64() < p
+	}
+}
+
+// KeepSlowOrFailed returns a Recorder.Keep function which only keeps entries
+// with a response status of at least minStatus, or which took longer than
+// slow. Either condition is ignored if zero, and if both are zero every entry
+// is kept, as if Keep were nil.
+func KeepSlowOrFailed(minStatus int, slow time.Duration) func(*Entry) bool {
+	return func(e *Entry) bool {
+		if minStatus <= 0 && slow <= 0 {
+			return true
+		}
+		if minStatus > 0 && e.Response.StatusCode >= minStatus {
+			return true
+		}
+		return slow > 0 && time.Duration(e.Time)*time.Millisecond > slow
+	}
+}
+
+// shouldRecord returns true if req should be recorded, based on the paused
+// state and sampling configuration.
+func (c *Recorder) shouldRecord(req *http.Request) bool {
+	if !c.Enabled() {
+		return false
+	}
+	return c.Sampler == nil || c.Sampler(req)
+}
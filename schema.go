@@ -0,0 +1,91 @@
+package harhar
+
+//go:generate go run ./cmd/harhar schema -o har.schema.json
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the documents
+// written by this package, including all of harhar's "_"-prefixed vendor
+// extension fields. It is generated from the Go structs, so it always
+// matches the encoder. Other "_"-prefixed fields are permitted, as allowed
+// by the HAR specification.
+func JSONSchema() map[string]interface{} {
+	defs := make(map[string]interface{})
+	root := schemaFor(reflect.TypeOf(HAR{}), defs)
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = "https://github.com/pbnjay/harhar/har.schema.json"
+	root["title"] = "HTTP Archive (harhar)"
+	root["$defs"] = defs
+	return root
+}
+
+func schemaFor(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		// arbitrary JSON value
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem(), defs)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		// nil slices are encoded as null
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": schemaFor(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": schemaFor(t.Elem(), defs)}
+	case reflect.Struct:
+		if t != reflect.TypeOf(HAR{}) {
+			ref := map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+			if _, ok := defs[t.Name()]; ok {
+				return ref
+			}
+			defs[t.Name()] = nil // placeholder for recursive types
+			defs[t.Name()] = structSchema(t, defs)
+			return ref
+		}
+		return structSchema(t, defs)
+	}
+	return map[string]interface{}{}
+}
+
+func structSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	props := make(map[string]interface{})
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = schemaFor(f.Type, defs)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"patternProperties":    map[string]interface{}{"^_": map[string]interface{}{}},
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,176 @@
+package harhar
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// DefaultScrubHeaders lists headers which commonly carry credentials.
+var DefaultScrubHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-Api-Key", "X-Auth-Token", "X-Csrf-Token",
+}
+
+// ScrubOptions controls how Scrub sanitizes an archive.
+type ScrubOptions struct {
+	// Headers lists header names or glob patterns to remove from requests
+	// and responses. Removing Cookie or Set-Cookie also removes the parsed
+	// cookies.
+	Headers []string
+
+	// Mask lists patterns whose matches are replaced with "[REDACTED]" in
+	// URLs, header values, query parameters, cookies, bodies, GraphQL
+	// variables, text WebSocket messages and error messages.
+	Mask []*regexp.Regexp
+
+	// StripBodies removes all request and response bodies.
+	StripBodies bool
+
+	// RewriteHosts replaces each host name (key) with another (value)
+	// wherever it appears.
+	RewriteHosts map[string]string
+}
+
+// Scrub sanitizes the archive in place, so it can be shared safely. Note
+// that bodies stored externally (BodyType.FileRef) are not modified.
+func (h *HAR) Scrub(opts ScrubOptions) {
+	if opts.StripBodies {
+		h.StripBodies()
+	}
+	var oldnew []string
+	for from, to := range opts.RewriteHosts {
+		oldnew = append(oldnew, from, to)
+	}
+	hosts := strings.NewReplacer(oldnew...)
+
+	rewrite := func(s string) string {
+		for _, re := range opts.Mask {
+			s = re.ReplaceAllString(s, "[REDACTED]")
+		}
+		if len(oldnew) > 0 {
+			s = hosts.Replace(s)
+		}
+		return s
+	}
+
+	for i := range h.Log.Entries {
+		ent := &h.Log.Entries[i]
+		if len(opts.Headers) > 0 {
+			keep := func(name string) bool { return !matchHeader(opts.Headers, name) }
+			ent.Request.Headers = filterPairsBy(ent.Request.Headers, keep)
+			ent.Response.Headers = filterPairsBy(ent.Response.Headers, keep)
+			if !keep("Cookie") {
+				ent.Request.Cookies = []Cookie{}
+			}
+			if !keep("Set-Cookie") {
+				ent.Response.Cookies = []Cookie{}
+			}
+		}
+		if len(opts.Mask) > 0 || len(oldnew) > 0 {
+			scrubStrings(ent, rewrite)
+		}
+	}
+	for i := range h.Log.Pages {
+		h.Log.Pages[i].Title = rewrite(h.Log.Pages[i].Title)
+	}
+}
+
+// filterPairsBy returns the pairs whose names are accepted by keep.
+func filterPairsBy(pairs []NameValuePair, keep func(string) bool) []NameValuePair {
+	kept := pairs[:0]
+	for _, p := range pairs {
+		if keep(p.Name) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// scrubStrings applies fn to every string in the entry which may contain
+// sensitive data.
+func scrubStrings(ent *Entry, fn func(string) string) {
+	pairs := func(ps []NameValuePair) {
+		for i := range ps {
+			ps[i].Value = fn(ps[i].Value)
+		}
+	}
+	cookies := func(cs []Cookie) {
+		for i := range cs {
+			cs[i].Value = fn(cs[i].Value)
+			cs[i].Domain = fn(cs[i].Domain)
+		}
+	}
+
+	req, resp := &ent.Request, &ent.Response
+	req.URL = fn(req.URL)
+	pairs(req.Headers)
+	pairs(req.QueryParams)
+	pairs(req.Trailers)
+	cookies(req.Cookies)
+	if text := req.Body.Text(); text != "" && req.Body.Encoding == "" {
+		req.Body.Content = fn(text)
+		req.Body.compressed = nil
+	}
+	for i := range req.Body.Params {
+		req.Body.Params[i].Value = fn(req.Body.Params[i].Value)
+	}
+	if req.GraphQL != nil {
+		req.GraphQL.Variables = scrubJSON(req.GraphQL.Variables, fn)
+	}
+
+	resp.RedirectURL = fn(resp.RedirectURL)
+	resp.RedirectTarget = fn(resp.RedirectTarget)
+	pairs(resp.Headers)
+	pairs(resp.Trailers)
+	cookies(resp.Cookies)
+	if text := resp.Body.Text(); text != "" && resp.Body.Encoding == "" {
+		resp.Body.Content = fn(text)
+		resp.Body.compressed = nil
+	}
+	for i := range ent.EarlyHints {
+		pairs(ent.EarlyHints[i].Headers)
+	}
+	for i, m := range ent.WebSocketMessages {
+		if m.Opcode == 1 {
+			ent.WebSocketMessages[i].Data = fn(m.Data)
+		}
+	}
+	ent.Error = fn(ent.Error)
+}
+
+// scrubJSON applies fn to every string value in raw, keeping it valid JSON.
+// Object keys are left as they are.
+func scrubJSON(raw json.RawMessage, fn func(string) string) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return raw
+	}
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch x := v.(type) {
+		case string:
+			return fn(x)
+		case []interface{}:
+			for i := range x {
+				x[i] = walk(x[i])
+			}
+		case map[string]interface{}:
+			for k := range x {
+				x[k] = walk(x[k])
+			}
+		}
+		return v
+	}
+	out, err := json.Marshal(walk(v))
+	if err != nil {
+		return raw
+	}
+	return out
+}
@@ -1,60 +1,138 @@
 package harhar
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"time"
 )
 
 // ServeHTTP implements http.Handler (aka a Server-side recorder)
 func (c *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.serve(w, req, c.Handler)
+}
+
+// Middleware returns a function which wraps a handler with server-side
+// recording into rec, for use with standard middleware chains:
+//
+//	router.Use(harhar.Middleware(rec))
+//
+// The recorder's own Handler field is ignored.
+func Middleware(rec *Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rec.serve(w, req, next)
+		})
+	}
+}
+
+// serve records the exchange of req being handled by next.
+func (c *Recorder) serve(w http.ResponseWriter, req *http.Request, next http.Handler) {
+	if !c.shouldRecord(req) {
+		next.ServeHTTP(w, req)
+		return
+	}
 
 	var err error
 	ent := Entry{}
-	ent.Request, err = makeRequest(req)
+	startTime := c.now()
+	captureStart := time.Now()
+	if c.RequestIDHeader != "" {
+		ent.RequestID = req.Header.Get(c.RequestIDHeader)
+	}
+	ent.Request, err = makeRequest(req, c.OmitUploadContents)
 	if err != nil {
 		log.Println("unable to record HAR for request ", req.URL.String())
 	}
+	// reading the request body stands in for the client's send time
+	handlerStart := c.now()
+	overhead := time.Since(captureStart)
+	ent.Timings.Send = int(handlerStart.Sub(startTime).Milliseconds())
 
-	responseWrapper := &HARResponseWriter{}
-
-	startTime := time.Now()
-	c.Handler.ServeHTTP(responseWrapper, req)
-	ent.Time = int(time.Since(startTime).Milliseconds())
-	ent.Start = startTime.Format(time.RFC3339Nano)
-	ent.Timings.Send = -1
-	ent.Timings.Receive = -1
+	// response bytes are passed through to w as they are written, so
+	// streaming handlers (SSE, long-polling) keep working
+	responseWrapper := &HARResponseWriter{w: w, MaxBodySize: c.MaxBodySize, now: c.now, start: startTime}
+	if isWebSocket(req) {
+		responseWrapper.webSocket = &wsCapture{now: c.now}
+	}
 
-	// copy headers
-	for h, vals := range responseWrapper.header {
-		for _, val := range vals {
-			w.Header().Set(h, val)
+	ctx, cacheState := withCacheSlot(req.Context())
+	next.ServeHTTP(responseWrapper, req.WithContext(ctx))
+	if responseWrapper.hijacked {
+		ent.Upgraded = true
+		if responseWrapper.webSocket != nil {
+			ent.WebSocketMessages = responseWrapper.webSocket.messages()
 		}
+	} else if !responseWrapper.didWriteHeaders {
+		responseWrapper.WriteHeader(http.StatusOK)
 	}
-	w.WriteHeader(responseWrapper.statusCode)
-	w.Write(responseWrapper.body.Bytes())
+	endTime := c.now()
+	if responseWrapper.firstByte.IsZero() {
+		// headers only, sent when the handler returned
+		responseWrapper.firstByte = endTime
+	}
+	ent.Time = int(endTime.Sub(startTime).Milliseconds())
+	ent.Start = startTime.Format(time.RFC3339Nano)
+
+	// Wait is the handler's time to first byte, Receive is the remainder
+	// of the time spent writing the response
+	ent.Timings.Wait = int(responseWrapper.firstByte.Sub(handlerStart).Milliseconds())
+	ent.Timings.Receive = int(endTime.Sub(responseWrapper.firstByte).Milliseconds())
 
 	resp := responseWrapper.AsResponse(req)
-	ent.Response, err = makeResponse(resp)
+	ent.Response, err = makeResponse(resp, endTime)
 	if err != nil {
 		log.Println("unable to record HAR for response ", req.URL.String())
 	}
-	c.HAR.Log.Entries = append(c.HAR.Log.Entries, ent)
+	ent.Response.BodySize = int(responseWrapper.written)
+	ent.EarlyHints = responseWrapper.earlyHints
+	ent.Cache = makeCache(req, resp, startTime)
+	if cacheState.set {
+		// described by a CachingTransport used by the handler
+		ent.Cache = cacheState.state
+	}
+	ent.SecurityDetails = makeSecurityDetails(req.TLS)
+	// the context is cancelled early if the client went away
+	ent.Cancelled = req.Context().Err() != nil
+	ent.Tags = TagsFromContext(req.Context())
+	c.correlate(req.Context(), req, &ent)
+	c.addEntry(ent, overhead)
 }
 
+// HARResponseWriter wraps an http.ResponseWriter, passing all writes through
+// while keeping a copy of the response for the HAR.
 type HARResponseWriter struct {
+	w          http.ResponseWriter
 	body       bytes.Buffer
 	statusCode int
 	header     http.Header
+	written    int64
+	firstByte  time.Time
+	now        func() time.Time
+	start      time.Time
+
+	// informational (1xx) responses sent before the final one
+	earlyHints []InformationalResponse
 
 	didWriteHeaders bool
+	hijacked        bool
+
+	// captures the messages of a WebSocket connection once hijacked
+	webSocket *wsCapture
+
+	// MaxBodySize limits the number of response bytes copied into the HAR.
+	// The full response is always written to the client. 0 means no limit.
+	MaxBodySize int
 }
 
 func (w *HARResponseWriter) Header() http.Header {
+	if w.w != nil {
+		return w.w.Header()
+	}
 	if w.header == nil {
 		w.header = make(http.Header, 5)
 	}
@@ -65,19 +143,132 @@ func (w *HARResponseWriter) Write(b []byte) (int, error) {
 	if !w.didWriteHeaders {
 		w.WriteHeader(http.StatusOK)
 	}
-	return w.body.Write(b)
+	if w.firstByte.IsZero() {
+		w.firstByte = w.clock()
+	}
+
+	keep := b
+	if w.MaxBodySize > 0 {
+		if room := w.MaxBodySize - w.body.Len(); room < len(keep) {
+			if room < 0 {
+				room = 0
+			}
+			keep = keep[:room]
+		}
+	}
+	w.body.Write(keep)
+
+	if w.w == nil {
+		w.written += int64(len(b))
+		return len(b), nil
+	}
+	n, err := w.w.Write(b)
+	w.written += int64(n)
+	return n, err
 }
 
 func (w *HARResponseWriter) WriteHeader(statusCode int) {
+	if w.didWriteHeaders {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		// informational responses may be followed by any number of others
+		// and then the final response
+		info := InformationalResponse{StatusCode: statusCode}
+		if !w.start.IsZero() {
+			info.Time = int(w.clock().Sub(w.start).Milliseconds())
+		}
+		for name, vals := range w.Header() {
+			for _, val := range vals {
+				info.Headers = append(info.Headers, NameValuePair{Name: name, Value: val})
+			}
+		}
+		w.earlyHints = append(w.earlyHints, info)
+		if w.w != nil {
+			w.w.WriteHeader(statusCode)
+		}
+		return
+	}
 	w.statusCode = statusCode
 	w.didWriteHeaders = true
+
+	// snapshot the headers as sent, later changes are trailers
+	w.header = w.Header().Clone()
+	if w.w != nil {
+		w.w.WriteHeader(statusCode)
+	}
+}
+
+func (w *HARResponseWriter) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
+
+// Flush implements http.Flusher, sending any buffered data to the client.
+func (w *HARResponseWriter) Flush() {
+	if !w.didWriteHeaders {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.firstByte.IsZero() {
+		w.firstByte = w.clock()
+	}
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, handing the connection over to the handler
+// (e.g. for a WebSocket upgrade). The request and upgrade handshake are still
+// recorded, but nothing sent over the connection afterwards.
+func (w *HARResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("harhar: underlying ResponseWriter does not support Hijack")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return conn, rw, err
+	}
+	w.hijacked = true
+	if w.webSocket != nil {
+		// anything already buffered was sent by the client before the
+		// hijack, and the handshake response is written through the
+		// connection if it hasn't been sent yet
+		buffered, _ := rw.Reader.Peek(rw.Reader.Buffered())
+		buffered = append([]byte(nil), buffered...)
+		w.webSocket.capture("send", buffered)
+		w.webSocket.received.skipHead = !w.didWriteHeaders
+		wc := &wsServerConn{Conn: conn, ws: w.webSocket}
+		conn = wc
+		rw = bufio.NewReadWriter(bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), wc)), bufio.NewWriter(wc))
+	}
+	if !w.didWriteHeaders {
+		// the handshake response is written directly to the connection,
+		// so assume the upgrade succeeded
+		w.statusCode = http.StatusSwitchingProtocols
+		w.header = w.Header().Clone()
+		w.didWriteHeaders = true
+		w.firstByte = w.clock()
+	}
+	return conn, rw, nil
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by
+// http.ResponseController.
+func (w *HARResponseWriter) Unwrap() http.ResponseWriter {
+	return w.w
 }
 
 func (w *HARResponseWriter) AsResponse(req *http.Request) *http.Response {
 	resp := &http.Response{
 		StatusCode: w.statusCode,
 		Proto:      req.Proto,
+		ProtoMajor: req.ProtoMajor,
+		ProtoMinor: req.ProtoMinor,
 		Header:     w.header.Clone(),
+		Request:    req,
 		Body:       io.NopCloser(bytes.NewReader(w.body.Bytes())),
 	}
 	return resp
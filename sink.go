@@ -0,0 +1,188 @@
+package harhar
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Sink is a destination for recorded archives, such as a local file or a
+// remote collector. Sinks are used by Recorder.Save and Recorder.AutoSave.
+type Sink interface {
+	Write(h *HAR) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(h *HAR) error
+
+// Write implements Sink.
+func (f SinkFunc) Write(h *HAR) error {
+	return f(h)
+}
+
+// FileSink writes archives to a local file, replacing its contents each time.
+type FileSink struct {
+	Filename string
+
+	// Index also writes a sidecar index (see HAR.WriteFileIndexed).
+	Index bool
+}
+
+// Write implements Sink.
+func (s *FileSink) Write(h *HAR) error {
+	var err error
+	if s.Index {
+		_, err = h.WriteFileIndexed(s.Filename)
+	} else {
+		_, err = h.WriteFile(s.Filename)
+	}
+	return err
+}
+
+// WriterSink encodes archives to an io.Writer, one JSON document per line.
+type WriterSink struct {
+	W io.Writer
+}
+
+// Write implements Sink.
+func (s *WriterSink) Write(h *HAR) error {
+	if _, err := h.WriteTo(s.W); err != nil {
+		return err
+	}
+	_, err := s.W.Write([]byte{'\n'})
+	return err
+}
+
+// HTTPSink uploads archives with a POST request to URL, e.g. a collector
+// service or a pre-signed object storage URL (set Method to PUT).
+type HTTPSink struct {
+	URL string
+
+	// Method defaults to POST.
+	Method string
+
+	// Header is added to every upload request.
+	Header http.Header
+
+	// Gzip compresses the upload with Content-Encoding: gzip.
+	Gzip bool
+
+	// Client defaults to http.DefaultClient. It should not be a client
+	// recording through the same Recorder.
+	Client *http.Client
+}
+
+// Write implements Sink.
+func (s *HTTPSink) Write(h *HAR) error {
+	pr, pw := io.Pipe()
+	go func() {
+		var w io.Writer = pw
+		var zw *gzip.Writer
+		if s.Gzip {
+			zw = gzip.NewWriter(pw)
+			w = zw
+		}
+		_, err := h.WriteTo(w)
+		if err == nil && zw != nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	method := s.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, s.URL, pr)
+	if err != nil {
+		pr.Close()
+		return err
+	}
+	for name, vals := range s.Header {
+		req.Header[name] = vals
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	cli := s.Client
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("harhar: upload to %s failed: %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+// Save writes a snapshot of the HAR recorded so far to s.
+func (c *Recorder) Save(s Sink) error {
+	return s.Write(c.Snapshot())
+}
+
+// AutoSave saves a snapshot of the HAR to s every interval, whenever entries
+// have been recorded or removed since the last save. Errors are passed to
+// onError, which may be nil. The returned stop function ends autosaving and
+// performs a final save.
+func (c *Recorder) AutoSave(s Sink, every time.Duration, onError func(error)) (stop func() error) {
+	// compare sequence numbers, as the count can come back to the same value
+	// after Clear, Rotate or FlushAt remove entries
+	var saved uint64
+	count := -1
+	return periodically(every, onError, func() error {
+		h, last := c.snapshot()
+		if last == saved && len(h.Log.Entries) == count {
+			return nil
+		}
+		err := s.Write(h)
+		if err == nil {
+			saved, count = last, len(h.Log.Entries)
+		}
+		return err
+	})
+}
+
+// periodically calls fn every interval until the returned stop function is
+// called, which calls fn one final time and returns its error.
+func periodically(every time.Duration, onError func(error), fn func() error) (stop func() error) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if err := fn(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+			err = fn()
+		})
+		return err
+	}
+}
@@ -0,0 +1,41 @@
+package harhar
+
+// approximate fixed cost of an entry's structs, slices and strings
+const entryOverhead = 512
+
+// EstimatedSize returns the approximate number of bytes of memory used by the
+// entries currently held by the recorder, so callers can flush or rotate when
+// a budget is exceeded. It is updated as entries are added and removed.
+func (c *Recorder) EstimatedSize() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// estimateSize approximates the memory used by an entry.
+func estimateSize(e *Entry) int64 {
+	n := int64(entryOverhead + len(e.Start) + len(e.Request.Method) + len(e.Request.URL))
+	pairs := func(ps []NameValuePair) {
+		for _, p := range ps {
+			n += int64(len(p.Name) + len(p.Value) + 32)
+		}
+	}
+	pairs(e.Request.Headers)
+	pairs(e.Request.QueryParams)
+	pairs(e.Request.Trailers)
+	pairs(e.Response.Headers)
+	pairs(e.Response.Trailers)
+	for _, ck := range e.Request.Cookies {
+		n += int64(len(ck.Name) + len(ck.Value) + 96)
+	}
+	for _, ck := range e.Response.Cookies {
+		n += int64(len(ck.Name) + len(ck.Value) + 96)
+	}
+	for _, p := range e.Request.Body.Params {
+		n += int64(len(p.Name) + len(p.Value) + len(p.FileName) + 64)
+	}
+	n += int64(len(e.Request.Body.Content) + len(e.Request.Body.compressed))
+	n += int64(len(e.Response.Body.Content) + len(e.Response.Body.compressed))
+	n += int64(len(e.Response.Chunks) * 16)
+	return n
+}
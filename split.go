@@ -0,0 +1,55 @@
+package harhar
+
+import (
+	"sort"
+	"time"
+)
+
+// Split divides the archive into time-bucketed archives, each covering a
+// window of the given duration aligned to multiples of every. Entries whose
+// start time cannot be parsed are placed into the first bucket. Pages
+// referenced by the entries of a bucket are copied into it, so each archive
+// stands alone. Empty windows are omitted, and archives are returned in
+// chronological order.
+func (h *HAR) Split(every time.Duration) []*HAR {
+	if every <= 0 || len(h.Log.Entries) == 0 {
+		return []*HAR{h}
+	}
+
+	buckets := make(map[int64]*HAR)
+	var keys []int64
+	for _, ent := range h.Log.Entries {
+		var key int64
+		if t := ent.StartTime(); !t.IsZero() {
+			key = t.Truncate(every).UnixNano()
+		}
+		b, ok := buckets[key]
+		if !ok {
+			b = &HAR{Log: h.Log}
+			b.Log.Pages = nil
+			b.Log.Entries = nil
+			buckets[key] = b
+			keys = append(keys, key)
+		}
+		b.Log.Entries = append(b.Log.Entries, ent)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	res := make([]*HAR, 0, len(keys))
+	for _, key := range keys {
+		b := buckets[key]
+		refs := make(map[string]bool)
+		for _, ent := range b.Log.Entries {
+			if ent.PageRef != "" {
+				refs[ent.PageRef] = true
+			}
+		}
+		for _, p := range h.Log.Pages {
+			if refs[p.ID] {
+				b.Log.Pages = append(b.Log.Pages, p)
+			}
+		}
+		res = append(res, b)
+	}
+	return res
+}
@@ -0,0 +1,155 @@
+package harhar
+
+import (
+	"io"
+	"net/url"
+	"sort"
+)
+
+// Summary describes the traffic in an archive, see Summarize.
+type Summary struct {
+	// Stats for all entries
+	Stats
+
+	// ByHost and ByEndpoint break the stats down by request host, and by
+	// method and templated path (see Entry.Endpoint).
+	ByHost     map[string]*Stats
+	ByEndpoint map[string]*Stats
+
+	// ByStatus counts entries by response status code.
+	ByStatus map[int]int
+
+	// ByTag counts entries by tag (see WithTags).
+	ByTag map[string]int
+}
+
+// Stats are the totals and latency percentiles of a group of entries.
+type Stats struct {
+	Count int
+
+	// Bytes transferred: headers and bodies of requests and responses, where
+	// known.
+	Bytes int64
+
+	// Time is the sum of the entries' total times, and Max the longest, in
+	// milliseconds.
+	Time int64
+	Max  int
+
+	// Latency percentiles of the total entry time, in milliseconds.
+	P50, P95, P99 int
+
+	times []int
+}
+
+func (s *Stats) add(e *Entry) {
+	s.Count++
+	s.Bytes += transferred(e)
+	s.Time += int64(e.Time)
+	if e.Time > s.Max {
+		s.Max = e.Time
+	}
+	s.times = append(s.times, e.Time)
+}
+
+func (s *Stats) finish() {
+	sort.Ints(s.times)
+	s.P50 = percentile(s.times, 50)
+	s.P95 = percentile(s.times, 95)
+	s.P99 = percentile(s.times, 99)
+	s.times = nil
+}
+
+// Summarize computes traffic statistics for the archive.
+func Summarize(h *HAR) *Summary {
+	sum := newSummary()
+	for i := range h.Log.Entries {
+		sum.add(&h.Log.Entries[i])
+	}
+	sum.finish()
+	return sum
+}
+
+// SummarizeStream computes traffic statistics for the entries of the HAR
+// document read from r which are matched by opts, decoding one entry at a
+// time (see Stream).
+func SummarizeStream(r io.Reader, opts FilterOptions) (*Summary, error) {
+	sum := newSummary()
+	err := Stream(r, func(e Entry) error {
+		if opts.Match(&e) {
+			sum.add(&e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sum.finish()
+	return sum, nil
+}
+
+func newSummary() *Summary {
+	return &Summary{
+		ByHost:     make(map[string]*Stats),
+		ByEndpoint: make(map[string]*Stats),
+		ByStatus:   make(map[int]int),
+		ByTag:      make(map[string]int),
+	}
+}
+
+func (sum *Summary) add(e *Entry) {
+	group := func(m map[string]*Stats, key string) *Stats {
+		s, ok := m[key]
+		if !ok {
+			s = &Stats{}
+			m[key] = s
+		}
+		return s
+	}
+
+	sum.Stats.add(e)
+	host := ""
+	if u, err := url.Parse(e.Request.URL); err == nil {
+		host = u.Host
+	}
+	group(sum.ByHost, host).add(e)
+	group(sum.ByEndpoint, e.Endpoint()).add(e)
+	sum.ByStatus[e.Response.StatusCode]++
+	for _, t := range e.Tags {
+		sum.ByTag[t]++
+	}
+}
+
+func (sum *Summary) finish() {
+	sum.Stats.finish()
+	for _, s := range sum.ByHost {
+		s.finish()
+	}
+	for _, s := range sum.ByEndpoint {
+		s.finish()
+	}
+}
+
+// transferred returns the known header and body sizes of the entry.
+func transferred(e *Entry) int64 {
+	var n int64
+	for _, size := range []int{e.Request.HeadersSize, e.Request.BodySize, e.Response.HeadersSize, e.Response.BodySize} {
+		if size > 0 {
+			// -1 means unknown
+			n += int64(size)
+		}
+	}
+	return n
+}
+
+// percentile returns the p-th percentile of sorted values (nearest rank).
+func percentile(sorted []int, p int) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
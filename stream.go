@@ -0,0 +1,191 @@
+package harhar
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Stream decodes the HAR document from r one entry at a time, calling fn for
+// each, without loading the whole archive into memory. Gzip-compressed
+// documents are decompressed transparently. Decoding stops at the first error
+// returned by fn, which is returned by Stream.
+func Stream(r io.Reader, fn func(Entry) error) error {
+	return streamLog(r, nil, fn)
+}
+
+// streamLog decodes the HAR document from r, calling onEntry for each entry
+// and onField (if not nil) with every other field of the log, in the order
+// they appear.
+func streamLog(r io.Reader, onField func(key string, raw json.RawMessage) error, onEntry func(Entry) error) error {
+	r, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "log" {
+			if err = skipValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = expectDelim(dec, '{'); err != nil {
+			return err
+		}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if key != "entries" {
+				var raw json.RawMessage
+				if err = dec.Decode(&raw); err != nil {
+					return err
+				}
+				if name, ok := key.(string); ok && onField != nil {
+					if err = onField(name, raw); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			if err = expectDelim(dec, '['); err != nil {
+				return err
+			}
+			for dec.More() {
+				var ent Entry
+				if err = dec.Decode(&ent); err != nil {
+					return err
+				}
+				if err = onEntry(ent); err != nil {
+					return err
+				}
+			}
+			if err = expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		}
+		if err = expectDelim(dec, '}'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// FilterStream copies the HAR document from r to w, keeping only the entries
+// matched by opts, without loading the whole archive into memory (see
+// Filter). Pages are kept if any of their entries are, and are written after
+// the entries. It returns the number of entries kept and read.
+func FilterStream(r io.Reader, w io.Writer, opts FilterOptions) (kept, total int, err error) {
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	var pages []Page
+	refs := make(map[string]bool)
+
+	// fields are copied as they are read, except for pages
+	sep := []byte(`{"log":{`)
+	writeKey := func(key string) {
+		cw.Write(sep)
+		sep = []byte{','}
+		k, _ := json.Marshal(key)
+		cw.Write(k)
+		cw.Write([]byte{':'})
+	}
+	// whether the entries array has been started and ended
+	opened, closed := false, false
+	openEntries := func() {
+		writeKey("entries")
+		cw.Write([]byte{'['})
+		opened = true
+	}
+	closeEntries := func() {
+		if !opened {
+			openEntries()
+		}
+		cw.Write([]byte{']'})
+		closed = true
+	}
+
+	err = streamLog(r, func(key string, raw json.RawMessage) error {
+		if opened && !closed {
+			closeEntries()
+		}
+		if key == "pages" {
+			return json.Unmarshal(raw, &pages)
+		}
+		writeKey(key)
+		cw.Write(raw)
+		return cw.err
+	}, func(ent Entry) error {
+		total++
+		if !opts.Match(&ent) {
+			return nil
+		}
+		data, err := json.Marshal(&ent)
+		if err != nil {
+			return err
+		}
+		if !opened {
+			openEntries()
+		} else {
+			cw.Write([]byte{','})
+		}
+		cw.Write(data)
+		kept++
+		refs[ent.PageRef] = true
+		return cw.err
+	})
+	if err != nil {
+		return kept, total, err
+	}
+	if !closed {
+		closeEntries()
+	}
+
+	var keptPages []Page
+	for _, pg := range pages {
+		if refs[pg.ID] {
+			keptPages = append(keptPages, pg)
+		}
+	}
+	if len(keptPages) > 0 {
+		data, err := json.Marshal(keptPages)
+		if err != nil {
+			return kept, total, err
+		}
+		writeKey("pages")
+		cw.Write(data)
+	}
+	cw.Write([]byte("}}"))
+	if cw.err != nil {
+		return kept, total, cw.err
+	}
+	return kept, total, bw.Flush()
+}
+
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != d {
+		return fmt.Errorf("harhar: expected '%s' in HAR document, got %v", d, tok)
+	}
+	return nil
+}
+
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
@@ -1,6 +1,9 @@
 package harhar
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // HAR represents the root of an HTTP Archive document.
 //
@@ -55,10 +58,50 @@ type Log struct {
 	// through this Client.
 	Entries []Entry `json:"entries"`
 
+	// Environment describes the host and client configuration which
+	// produced this log, if captured.
+	Environment *Environment `json:"_environment,omitempty"`
+
 	// Comment can be added to the log to describe the particulars of this data.
 	Comment string `json:"comment,omitempty"`
 }
 
+// Environment is a fingerprint of the recording host and client configuration.
+type Environment struct {
+	// GoVersion used to build the recording program
+	GoVersion string `json:"goVersion"`
+	// OS and Arch of the recording host
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// Hostname of the recording host
+	Hostname string `json:"hostname,omitempty"`
+	// ProxyEnv contains the proxy environment variables that were set
+	ProxyEnv []NameValuePair `json:"proxyEnv,omitempty"`
+	// Transport settings of the recording client, if known
+	Transport *TransportInfo `json:"transport,omitempty"`
+}
+
+// TransportInfo describes the settings of an http.Transport.
+type TransportInfo struct {
+	TLSHandshakeTimeout   string `json:"tlsHandshakeTimeout"`
+	IdleConnTimeout       string `json:"idleConnTimeout"`
+	ResponseHeaderTimeout string `json:"responseHeaderTimeout"`
+	ExpectContinueTimeout string `json:"expectContinueTimeout"`
+
+	MaxIdleConns        int `json:"maxIdleConns"`
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"`
+	MaxConnsPerHost     int `json:"maxConnsPerHost"`
+
+	DisableKeepAlives  bool `json:"disableKeepAlives"`
+	DisableCompression bool `json:"disableCompression"`
+	HTTP2              bool `json:"http2"`
+	UsesProxy          bool `json:"usesProxy"`
+
+	TLSMinVersion      string `json:"tlsMinVersion,omitempty"`
+	TLSMaxVersion      string `json:"tlsMaxVersion,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
 // Page represents a group of requests (e.g. an HTML document with multiple resources)
 type Page struct {
 	// Start of the page load (ISO 8601)
@@ -118,8 +161,100 @@ type Entry struct {
 	// Connection contains the connection info (e.g. a TCP/IP Port/ID)
 	Connection string `json:"connection,omitempty"`
 
+	// EarlyHints contains any informational (1xx) responses received before
+	// the final response, e.g. 100 Continue or 103 Early Hints.
+	EarlyHints []InformationalResponse `json:"_earlyHints,omitempty"`
+
+	// SecurityDetails describes the TLS connection, if one was used.
+	SecurityDetails *SecurityDetails `json:"_securityDetails,omitempty"`
+
+	// RequestID sent in Recorder.RequestIDHeader, if configured
+	RequestID string `json:"_requestId,omitempty"`
+
+	// Tags attached to the request context with WithTags
+	Tags []string `json:"_tags,omitempty"`
+
+	// TraceID and SpanID link the entry to a distributed trace, if one was
+	// active for the request.
+	TraceID string `json:"_traceId,omitempty"`
+	SpanID  string `json:"_spanId,omitempty"`
+
+	// Upgraded is true if the connection was hijacked by the handler after
+	// this request, or the response switched protocols, e.g. to WebSocket.
+	Upgraded bool `json:"_upgraded,omitempty"`
+
+	// WebSocketMessages sent over the connection after a WebSocket upgrade,
+	// in Chrome's format. The entry is recorded when the connection closes.
+	WebSocketMessages []WebSocketMessage `json:"_webSocketMessages,omitempty"`
+
+	// Cancelled is true if the request's context was cancelled (or its
+	// deadline exceeded) before the exchange completed. The response is
+	// whatever was received up to that point.
+	Cancelled bool `json:"_cancelled,omitempty"`
+
+	// Error is the error which ended the exchange before a response was
+	// received, see Recorder.RecordErrors.
+	Error string `json:"_error,omitempty"`
+
+	// Source names the archive this entry came from, when merged from several.
+	Source string `json:"_source,omitempty"`
+
 	// Comment can be added by the user
 	Comment string `json:"comment,omitempty"`
+
+	// seq numbers the entries added to a Recorder, starting from 1
+	seq uint64
+}
+
+// WebSocketMessage is a text or binary message sent over a WebSocket.
+type WebSocketMessage struct {
+	// Type is "send" for messages from the client, "receive" for messages
+	// from the server.
+	Type string `json:"type"`
+
+	// Time the message completed, in seconds since the Unix epoch.
+	Time float64 `json:"time"`
+
+	// Opcode is 1 for text messages and 2 for binary messages.
+	Opcode int `json:"opcode"`
+
+	// Data of the message. Binary and compressed messages are base64-encoded.
+	Data string `json:"data"`
+}
+
+// InformationalResponse describes an interim 1xx response.
+type InformationalResponse struct {
+	// StatusCode of the informational response, e.g. 103
+	StatusCode int `json:"status"`
+	// Headers sent with the informational response
+	Headers []NameValuePair `json:"headers,omitempty"`
+	// Time in milliseconds since the start of the request it was received
+	Time int `json:"time"`
+}
+
+// SecurityDetails describes the negotiated TLS parameters of a connection.
+type SecurityDetails struct {
+	// Protocol is the TLS version, e.g. "TLS 1.3"
+	Protocol string `json:"protocol"`
+	// Cipher is the negotiated cipher suite name
+	Cipher string `json:"cipher"`
+	// ALPN is the negotiated application protocol (e.g. "h2"), if any
+	ALPN string `json:"alpn,omitempty"`
+	// ServerName requested via SNI
+	ServerName string `json:"serverName,omitempty"`
+	// Resumed is true if the session was resumed from a previous connection
+	Resumed bool `json:"resumed,omitempty"`
+
+	// SubjectName of the leaf certificate presented by the server
+	SubjectName string `json:"subjectName,omitempty"`
+	// SANList contains the subject alternative names of the leaf certificate
+	SANList []string `json:"sanList,omitempty"`
+	// Issuer of the leaf certificate
+	Issuer string `json:"issuer,omitempty"`
+	// ValidFrom is the start of the certificate validity period (ISO 8601)
+	ValidFrom string `json:"validFrom,omitempty"`
+	// ValidTo is the certificate expiration time (ISO 8601)
+	ValidTo string `json:"validTo,omitempty"`
 }
 
 // CacheState represents the cache status before and after a request.
@@ -188,10 +323,45 @@ type Request struct {
 	// BodySize of the request body in bytes.
 	BodySize int `json:"bodySize"`
 
+	// Trailers sent after the request body
+	Trailers []NameValuePair `json:"_trailers,omitempty"`
+
+	// FetchMetadata sent by the browser (Sec-Fetch-* and Priority headers)
+	FetchMetadata *FetchMetadata `json:"_fetchMetadata,omitempty"`
+
+	// GraphQL describes the operation, if the body is a GraphQL request
+	GraphQL *GraphQL `json:"_graphql,omitempty"`
+
 	// Comment can be added by the user
 	Comment string `json:"comment,omitempty"`
 }
 
+// FetchMetadata describes the context of a browser request.
+type FetchMetadata struct {
+	// Site relation of the request initiator, from Sec-Fetch-Site
+	// (e.g. "same-origin", "cross-site")
+	Site string `json:"site,omitempty"`
+	// Mode of the request, from Sec-Fetch-Mode (e.g. "navigate", "cors")
+	Mode string `json:"mode,omitempty"`
+	// Dest is the requested resource type, from Sec-Fetch-Dest
+	// (e.g. "document", "image", "script")
+	Dest string `json:"dest,omitempty"`
+	// User is true if the request was triggered by user activation
+	User bool `json:"user,omitempty"`
+	// Priority header value (e.g. "u=0, i")
+	Priority string `json:"priority,omitempty"`
+}
+
+// GraphQL describes a GraphQL operation sent in a request body.
+type GraphQL struct {
+	// OperationName from the request, if given
+	OperationName string `json:"operationName,omitempty"`
+	// OperationType is "query", "mutation" or "subscription"
+	OperationType string `json:"operationType"`
+	// Variables passed with the operation, as sent
+	Variables json.RawMessage `json:"variables,omitempty"`
+}
+
 // BodyType contains information about the Body of a request
 type BodyType struct {
 	// MIMEType of the body content
@@ -200,6 +370,13 @@ type BodyType struct {
 	Params []PostNameValuePair `json:"params,omitempty"`
 	// Content of the post as plain text (exclusive with Params)
 	Content string `json:"text,omitempty"`
+	// FileRef names the file holding the content, if stored externally
+	FileRef string `json:"_fileRef,omitempty"`
+	// Encoding of Content, "base64" for binary bodies
+	Encoding string `json:"_encoding,omitempty"`
+
+	// gzipped Content, see Recorder.CompressBodies
+	compressed []byte
 }
 
 // PostNameValuePair contains the description and content of a POSTed name and value pair.
@@ -213,6 +390,10 @@ type PostNameValuePair struct {
 	FileName string `json:"fileName,omitempty"`
 	// ContentType of an uploaded file
 	ContentType string `json:"contentType,omitempty"`
+	// Size and SHA256 hash of an uploaded file, recorded in place of its
+	// contents (see Recorder.OmitUploadContents)
+	Size   int64  `json:"_size,omitempty"`
+	SHA256 string `json:"_sha256,omitempty"`
 
 	// Comment can be added by the user
 	Comment string `json:"comment,omitempty"`
@@ -229,9 +410,12 @@ type Response struct {
 	// HTTPVersion of the HTTP response
 	HTTPVersion string `json:"httpVersion"` // ex "HTTP/1.1"
 
-	// RedirectURL from the location header
+	// RedirectURL from the location header, for redirect (3xx) responses
 	RedirectURL string `json:"redirectURL"`
 
+	// RedirectTarget is RedirectURL resolved against the request URL
+	RedirectTarget string `json:"_redirectTarget,omitempty"`
+
 	// Cookies sent with the response
 	Cookies []Cookie `json:"cookies"`
 
@@ -250,6 +434,16 @@ type Response struct {
 	// BodySize of the response body in bytes (as sent)
 	BodySize int `json:"bodySize"`
 
+	// Trailers sent after the response body (e.g. gRPC-Web status)
+	Trailers []NameValuePair `json:"_trailers,omitempty"`
+
+	// Chunks describes how the response body arrived, if recorded.
+	Chunks []Chunk `json:"_chunks,omitempty"`
+
+	// Partial is true if the client closed the body before reading all of
+	// it, so Content and BodySize only cover what was read.
+	Partial bool `json:"_partial,omitempty"`
+
 	// Comment can be added by the user
 	Comment string `json:"comment,omitempty"`
 }
@@ -266,8 +460,21 @@ type BodyResponseType struct {
 	Content string `json:"text,omitempty"`
 	// Encoding used by the response.
 	Encoding string `json:"encoding,omitempty"`
+	// FileRef names the file holding the content, if stored externally
+	FileRef string `json:"_fileRef,omitempty"`
 	// Comment can be added by the user
 	Comment string `json:"comment,omitempty"`
+
+	// gzipped Content, see Recorder.CompressBodies
+	compressed []byte
+}
+
+// Chunk describes one piece of a streamed response body.
+type Chunk struct {
+	// Size of the chunk in bytes
+	Size int `json:"size"`
+	// Time in milliseconds after the response headers that the chunk arrived
+	Time int `json:"time"`
 }
 
 // NameValuePair is a name and value, paired.
@@ -295,6 +502,9 @@ type CacheInfo struct {
 	// HitCount is the number of the times the cached content has been opened.
 	HitCount int `json:"hitCount"`
 
+	// LastModified time of the cached content (ISO 8601), from the Last-Modified header.
+	LastModified string `json:"_lastModified,omitempty"`
+
 	// Comment can be added by the user
 	Comment string `json:"comment,omitempty"`
 }
@@ -315,4 +525,6 @@ type Cookie struct {
 	Secure bool `json:"secure,omitempty"`
 	// HTTPOnly flag status of the cookie.
 	HTTPOnly bool `json:"httpOnly,omitempty"`
+	// SameSite attribute of the cookie ("Strict", "Lax" or "None"), if set.
+	SameSite string `json:"_sameSite,omitempty"`
 }
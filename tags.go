@@ -0,0 +1,45 @@
+package harhar
+
+import "context"
+
+type tagsKey struct{}
+
+// WithTags returns a copy of ctx carrying tags, which are recorded on the
+// entry of any request made (or served) with the context. Tags accumulate
+// with any already present in ctx.
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	prev := TagsFromContext(ctx)
+	all := make([]string, 0, len(prev)+len(tags))
+	all = append(all, prev...)
+	all = append(all, tags...)
+	return context.WithValue(ctx, tagsKey{}, all)
+}
+
+// TagsFromContext returns the tags added to ctx by WithTags.
+func TagsFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(tagsKey{}).([]string)
+	return tags
+}
+
+// HasTag returns true if the entry is tagged with tag.
+func (e *Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyTag returns true if tags is empty, or the entry has any of them.
+func (e *Entry) hasAnyTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, t := range tags {
+		if e.HasTag(t) {
+			return true
+		}
+	}
+	return false
+}
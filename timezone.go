@@ -0,0 +1,26 @@
+package harhar
+
+import "time"
+
+// setTimeZone converts all timestamps in ent to loc.
+func setTimeZone(ent *Entry, loc *time.Location) {
+	conv := func(v *string) {
+		if t, err := time.Parse(time.RFC3339Nano, *v); err == nil {
+			*v = t.In(loc).Format(time.RFC3339Nano)
+		}
+	}
+	conv(&ent.Start)
+	for _, ci := range []*CacheInfo{ent.Cache.Before, ent.Cache.After} {
+		if ci != nil {
+			conv(&ci.Expires)
+			conv(&ci.LastAccess)
+			conv(&ci.LastModified)
+		}
+	}
+	for i := range ent.Request.Cookies {
+		conv(&ent.Request.Cookies[i].Expires)
+	}
+	for i := range ent.Response.Cookies {
+		conv(&ent.Response.Cookies[i].Expires)
+	}
+}
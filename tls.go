@@ -0,0 +1,49 @@
+package harhar
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// makeSecurityDetails summarizes a negotiated TLS connection for the
+// _securityDetails extension. Returns nil for plaintext connections.
+func makeSecurityDetails(cs *tls.ConnectionState) *SecurityDetails {
+	if cs == nil || !cs.HandshakeComplete {
+		return nil
+	}
+	sd := &SecurityDetails{
+		Protocol:   tlsVersionName(cs.Version),
+		Cipher:     tls.CipherSuiteName(cs.CipherSuite),
+		ALPN:       cs.NegotiatedProtocol,
+		ServerName: cs.ServerName,
+		Resumed:    cs.DidResume,
+	}
+	if len(cs.PeerCertificates) > 0 {
+		cert := cs.PeerCertificates[0]
+		sd.SubjectName = cert.Subject.String()
+		sd.Issuer = cert.Issuer.String()
+		sd.SANList = append(sd.SANList, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			sd.SANList = append(sd.SANList, ip.String())
+		}
+		sd.ValidFrom = cert.NotBefore.UTC().Format(time.RFC3339)
+		sd.ValidTo = cert.NotAfter.UTC().Format(time.RFC3339)
+	}
+	return sd
+}
+
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	case 0x0300:
+		return "SSL 3.0"
+	}
+	return "unknown"
+}
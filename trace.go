@@ -0,0 +1,65 @@
+package harhar
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// correlate records the trace and span IDs for the request into the entry,
+// from Recorder.TraceContext if set, or otherwise a W3C traceparent header.
+func (c *Recorder) correlate(ctx context.Context, req *http.Request, ent *Entry) {
+	if c.TraceContext != nil {
+		ent.TraceID, ent.SpanID = c.TraceContext(ctx)
+	}
+	if ent.TraceID == "" {
+		ent.TraceID, ent.SpanID = parseTraceparent(req.Header.Get("Traceparent"))
+	}
+	if c.TraceEvent != nil {
+		c.TraceEvent(ctx, ent)
+	}
+}
+
+// parseTraceparent extracts the trace and parent span IDs from a W3C Trace
+// Context traceparent header ("00-<trace-id>-<span-id>-<flags>").
+func parseTraceparent(v string) (traceID, spanID string) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", ""
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		// all-zero IDs are invalid
+		return "", ""
+	}
+	return parts[1], parts[2]
+}
+
+// injectRequestID returns req with the recorder's RequestIDHeader set, and
+// the ID. req is cloned rather than modified if a new ID is added.
+func (c *Recorder) injectRequestID(req *http.Request) (*http.Request, string) {
+	if c.RequestIDHeader == "" {
+		return req, ""
+	}
+	if id := req.Header.Get(c.RequestIDHeader); id != "" {
+		return req, id
+	}
+	id := c.newID()
+	req = req.Clone(req.Context())
+	req.Header.Set(c.RequestIDHeader, id)
+	return req, id
+}
+
+// stripRequestID removes the RequestIDHeader from the entry's request, if
+// configured to.
+func (c *Recorder) stripRequestID(ent *Entry) {
+	if !c.StripRequestIDHeader || c.RequestIDHeader == "" {
+		return
+	}
+	kept := ent.Request.Headers[:0]
+	for _, h := range ent.Request.Headers {
+		if !strings.EqualFold(h.Name, c.RequestIDHeader) {
+			kept = append(kept, h)
+		}
+	}
+	ent.Request.Headers = kept
+}
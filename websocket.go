@@ -0,0 +1,213 @@
+package harhar
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// frames larger than this stop the capture rather than being buffered
+const maxWebSocketFrame = 64 << 20
+
+// isWebSocket reports whether req asks to upgrade to the WebSocket protocol.
+func isWebSocket(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+// wsCapture collects the messages sent in both directions of a WebSocket
+// connection.
+type wsCapture struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	sent     wsParser
+	received wsParser
+	msgs     []WebSocketMessage
+}
+
+// capture decodes data read from or written to the connection, in the
+// direction given by typ ("send" or "receive").
+func (ws *wsCapture) capture(typ string, data []byte) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	p := &ws.sent
+	if typ == "receive" {
+		p = &ws.received
+	}
+	p.feed(data, func(opcode int, compressed bool, payload []byte) {
+		if opcode != 1 && opcode != 2 {
+			// control frames are not recorded
+			return
+		}
+		t := time.Now()
+		if ws.now != nil {
+			t = ws.now()
+		}
+		m := WebSocketMessage{Type: typ, Time: float64(t.UnixNano()) / 1e9, Opcode: opcode}
+		if opcode == 1 && !compressed && utf8.Valid(payload) {
+			m.Data = string(payload)
+		} else {
+			m.Data = base64.StdEncoding.EncodeToString(payload)
+		}
+		ws.msgs = append(ws.msgs, m)
+	})
+}
+
+func (ws *wsCapture) messages() []WebSocketMessage {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return append([]WebSocketMessage(nil), ws.msgs...)
+}
+
+// wsParser decodes the frames of one direction of a WebSocket connection
+// from the raw bytes, which may arrive split at any point.
+type wsParser struct {
+	buf []byte
+
+	// skipHead drops everything up to the end of an HTTP header block first,
+	// for connections which carry the upgrade response before any frames.
+	skipHead bool
+
+	// the fragmented message in progress, if any
+	opcode     int
+	compressed bool
+	msg        []byte
+
+	// set if the stream could not be decoded, ending the capture
+	broken bool
+}
+
+// feed adds data to the stream, calling emit for each complete message.
+func (p *wsParser) feed(data []byte, emit func(opcode int, compressed bool, payload []byte)) {
+	if p.broken {
+		return
+	}
+	p.buf = append(p.buf, data...)
+	if p.skipHead {
+		end := bytes.Index(p.buf, []byte("\r\n\r\n"))
+		if end < 0 {
+			return
+		}
+		p.buf = p.buf[end+4:]
+		p.skipHead = false
+	}
+
+	for len(p.buf) >= 2 {
+		fin := p.buf[0]&0x80 != 0
+		rsv1 := p.buf[0]&0x40 != 0
+		opcode := int(p.buf[0] & 0x0f)
+		masked := p.buf[1]&0x80 != 0
+		n := uint64(p.buf[1] & 0x7f)
+		hdr := 2
+		switch n {
+		case 126:
+			if len(p.buf) < 4 {
+				return
+			}
+			n = uint64(binary.BigEndian.Uint16(p.buf[2:]))
+			hdr = 4
+		case 127:
+			if len(p.buf) < 10 {
+				return
+			}
+			n = binary.BigEndian.Uint64(p.buf[2:])
+			hdr = 10
+		}
+		if n > maxWebSocketFrame {
+			p.broken = true
+			p.buf = nil
+			return
+		}
+		var mask []byte
+		if masked {
+			if len(p.buf) < hdr+4 {
+				return
+			}
+			mask = p.buf[hdr : hdr+4]
+			hdr += 4
+		}
+		if uint64(len(p.buf)-hdr) < n {
+			return
+		}
+
+		payload := append([]byte(nil), p.buf[hdr:hdr+int(n)]...)
+		if mask != nil {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+		p.buf = p.buf[hdr+int(n):]
+
+		switch {
+		case opcode >= 8:
+			// control frames may arrive between fragments
+			emit(opcode, false, payload)
+		case opcode == 0:
+			p.msg = append(p.msg, payload...)
+			if fin {
+				emit(p.opcode, p.compressed, p.msg)
+				p.msg = nil
+			}
+		case fin:
+			emit(opcode, rsv1, payload)
+		default:
+			p.opcode, p.compressed, p.msg = opcode, rsv1, payload
+		}
+	}
+}
+
+// wsBody wraps the read-write body of a client-side 101 Switching Protocols
+// response, capturing the messages passing through it. done is called once
+// with the messages when the body is closed.
+type wsBody struct {
+	io.ReadWriteCloser
+	ws   *wsCapture
+	once sync.Once
+	done func([]WebSocketMessage)
+}
+
+func (b *wsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadWriteCloser.Read(p)
+	b.ws.capture("receive", p[:n])
+	return n, err
+}
+
+func (b *wsBody) Write(p []byte) (int, error) {
+	n, err := b.ReadWriteCloser.Write(p)
+	b.ws.capture("send", p[:n])
+	return n, err
+}
+
+func (b *wsBody) Close() error {
+	err := b.ReadWriteCloser.Close()
+	b.once.Do(func() {
+		b.done(b.ws.messages())
+	})
+	return err
+}
+
+// wsServerConn wraps a connection hijacked from a server-side recorder, where
+// reads come from the client and writes go to it.
+type wsServerConn struct {
+	net.Conn
+	ws *wsCapture
+}
+
+func (c *wsServerConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.ws.capture("send", p[:n])
+	return n, err
+}
+
+func (c *wsServerConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.ws.capture("receive", p[:n])
+	return n, err
+}
@@ -0,0 +1,125 @@
+package harhar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// wsFrame encodes one WebSocket frame, masked if mask is set.
+func wsFrame(fin bool, opcode int, rsv1 bool, mask []byte, payload []byte) []byte {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	frame := []byte{b0}
+	b1 := byte(0)
+	if mask != nil {
+		b1 = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, b1|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, b1|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, b1|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if mask != nil {
+		frame = append(frame, mask...)
+		for i, c := range payload {
+			frame = append(frame, c^mask[i%4])
+		}
+		return frame
+	}
+	return append(frame, payload...)
+}
+
+func TestWSParser(t *testing.T) {
+	mask := []byte{1, 2, 3, 4}
+	long := bytes.Repeat([]byte("x"), 300)
+	huge := make([]byte, 10)
+	huge[0], huge[1] = 0x82, 127
+	binary.BigEndian.PutUint64(huge[2:], maxWebSocketFrame+1)
+
+	tests := []struct {
+		name     string
+		skipHead bool
+		stream   []byte
+		want     []string
+		broken   bool
+	}{
+		{
+			name:   "text",
+			stream: wsFrame(true, 1, false, nil, []byte("hello")),
+			want:   []string{"1 false hello"},
+		},
+		{
+			name:   "masked",
+			stream: wsFrame(true, 1, false, mask, []byte("hello")),
+			want:   []string{"1 false hello"},
+		},
+		{
+			name:   "binary",
+			stream: wsFrame(true, 2, false, nil, []byte{0, 1}),
+			want:   []string{"2 false \x00\x01"},
+		},
+		{
+			name:   "compressed",
+			stream: wsFrame(true, 1, true, nil, []byte("z")),
+			want:   []string{"1 true z"},
+		},
+		{
+			name:   "16-bit length",
+			stream: wsFrame(true, 2, false, mask, long),
+			want:   []string{"2 false " + string(long)},
+		},
+		{
+			name: "fragmented with a ping between",
+			stream: bytes.Join([][]byte{
+				wsFrame(false, 1, false, mask, []byte("hel")),
+				wsFrame(true, 9, false, mask, []byte("p")),
+				wsFrame(false, 0, false, mask, []byte("lo ")),
+				wsFrame(true, 0, false, mask, []byte("world")),
+			}, nil),
+			want: []string{"9 false p", "1 false hello world"},
+		},
+		{
+			name:     "after upgrade response",
+			skipHead: true,
+			stream:   append([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n\r\n"), wsFrame(true, 1, false, nil, []byte("hi"))...),
+			want:     []string{"1 false hi"},
+		},
+		{
+			name:   "oversized frame",
+			stream: append(huge, wsFrame(true, 1, false, nil, []byte("lost"))...),
+			broken: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// every split of the stream into two reads decodes the same
+			for split := 0; split <= len(tt.stream); split++ {
+				p := &wsParser{skipHead: tt.skipHead}
+				var got []string
+				emit := func(opcode int, compressed bool, payload []byte) {
+					got = append(got, fmt.Sprint(opcode, " ", compressed, " ", string(payload)))
+				}
+				p.feed(tt.stream[:split], emit)
+				p.feed(tt.stream[split:], emit)
+				if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+					t.Fatalf("split at %d: got %q, want %q", split, got, tt.want)
+				}
+				if p.broken != tt.broken {
+					t.Fatalf("split at %d: broken is %v, want %v", split, p.broken, tt.broken)
+				}
+			}
+		})
+	}
+}
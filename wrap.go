@@ -0,0 +1,38 @@
+package harhar
+
+import "net/http"
+
+// Wrap sets rt as the upstream RoundTripper which c records requests to, and
+// returns c, so the recorder can be slotted into an existing transport stack:
+//
+//	client.Transport = retry.New(harhar.NewRecorder().Wrap(oauthTransport))
+//
+// The recorder captures exactly what passes between the transport wrapping it
+// and rt. In the example above, Authorization headers added by the OAuth
+// transport are not recorded, and every retry attempt is recorded as its own
+// entry. Wrapping in the other order (oauth around the recorder) would record
+// the Authorization headers; wrapping the retry transport would record only
+// the final attempt.
+func (c *Recorder) Wrap(rt http.RoundTripper) *Recorder {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	c.RoundTripper = rt
+	return c
+}
+
+// MultiRecorder returns a RoundTripper which records every exchange into each
+// of recs before sending it to upstream, e.g. to keep a sampled archive with
+// bodies alongside a complete archive without them. The recorders are
+// chained in order, so recs[0] sees the request first, and each recorder's
+// RoundTripper is replaced.
+func MultiRecorder(upstream http.RoundTripper, recs ...*Recorder) http.RoundTripper {
+	rt := upstream
+	for i := len(recs) - 1; i >= 0; i-- {
+		rt = recs[i].Wrap(rt)
+	}
+	if rt == nil {
+		return http.DefaultTransport
+	}
+	return rt
+}